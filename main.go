@@ -7,82 +7,464 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"math"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/idealo/mongodb-benchmarking/mongobench"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run runs the benchmark; it returns instead of exiting, so the deferred samplers save their results and the clients
+// disconnect when a test fails
+func run() error {
 	var (
-		threads         int
-		docCount        int
-		uri             string
-		certificatePath string
-		testType        string
-		duration        int
-		runAll          bool
-		largeDocs       bool
-		dropDb          bool
+		threads          int
+		docCount         int
+		uri              string
+		certificatePath  string
+		testType         string
+		duration         int
+		runAll           bool
+		largeDocs        bool
+		dropDb           bool
+		retryWrites      bool
+		retryReads       bool
+		retryAttempts    int
+		retryBackoff     int
+		retryMaxBackoff  int
+		driverRetryStats bool
+		statsInterval    int
+		replLagInterval  int
+		maxReplLag       int
+		replLagAction    string
+		driverMetrics    bool
+		minPoolSize      int
+		maxPoolSize      int
+		maxConnecting    int
+		maxIdleTime      int
+		clients          int
+		pipelineFile     string
+		allowDiskUse     bool
+		batchSize        int
+		scanField        string
+		scanMin          int64
+		scanMax          int64
+		scanWidth        int64
+		scanSort         int
+		scanProjection   string
+		scanLimit        int64
+		indexFile        string
+		indexImpact      bool
+		indexBuildFile   string
+		indexBuildDelay  int
+		watchers         int
+		watchPipeline    string
+		fullDocument     string
+		timeSeries       bool
+		timeField        string
+		metaField        string
+		granularity      string
+		bucketMaxSpan    int
+		bucketRounding   int
+		sensors          int
+		sensorInterval   int
+		windowSize       int
+		shardKey         string
+		shardChunks      int
+		shardStats       int
+		queueItems       int
+		thinkTime        int
+		buckets          int
+		updateFile       string
+		replaceMinBytes  int
+		replaceMaxBytes  int
+		refillThreads    int
+		maxOps           int64
+		maxErrors        int64
+		latencySLO       int
+		sloWindow        int
+		dryRun           bool
+		dryRunLatency    int
+		dryRunFailures   float64
+		faultFile        string
+		verify           bool
+		readConfigs      string
+		staleReadKeys    int
+		readShare        float64
+		failover         bool
+		outputDir        string
 	)
 
 	flag.IntVar(&threads, "threads", 10, "Number of threads for inserting, updating, upserting, or deleting documents")
 	flag.IntVar(&docCount, "docs", 1000, "Total number of documents to insert, update, upsert, or delete")
 	flag.StringVar(&uri, "uri", "mongodb://localhost:27017", "MongoDB URI")
 	flag.StringVar(&certificatePath, "tlsCert", "", "Path to TLS certificate")
-	flag.StringVar(&testType, "type", "insert", "Test type: insert, update, upsert, or delete")
+	flag.StringVar(&testType, "type", "insert", "Test type: "+strings.Join(mongobench.WorkloadNames(), ", "))
 	flag.BoolVar(&runAll, "runAll", false, "Run all tests in order: insert, update, delete, upsert")
 	flag.IntVar(&duration, "duration", 0, "Duration in seconds to run the test")
 	flag.BoolVar(&largeDocs, "largeDocs", false, "Use large documents for testing")
 	flag.BoolVar(&dropDb, "dropDb", true, "Drop the database before running the test")
+	flag.BoolVar(&retryWrites, "retryWrites", true, "Enable the driver's retryable writes")
+	flag.BoolVar(&retryReads, "retryReads", true, "Enable the driver's retryable reads")
+	flag.IntVar(&retryAttempts, "retryAttempts", 1, "Total attempts per operation including application-level retries (1 disables them)")
+	flag.IntVar(&retryBackoff, "retryBackoff", 100, "Backoff in milliseconds before the first application-level retry, doubled for each further retry")
+	flag.IntVar(&retryMaxBackoff, "retryMaxBackoff", 5000, "Maximum backoff in milliseconds between application-level retries")
+	flag.BoolVar(&driverRetryStats, "driverRetryStats", false, "Count the retries of the driver's retryable writes and reads in the per-second output")
+	flag.IntVar(&statsInterval, "serverStatsInterval", 0, "Interval in seconds for sampling serverStatus and collStats during the test (0 disables sampling)")
+	flag.IntVar(&replLagInterval, "replLagInterval", 0, "Interval in seconds for sampling the replication lag of a replica set during the test (0 disables sampling)")
+	flag.IntVar(&maxReplLag, "maxReplLag", 0, "Replication lag threshold in seconds that triggers -replLagAction (0 disables it)")
+	flag.StringVar(&replLagAction, "replLagAction", "pause", "Action when the replication lag exceeds -maxReplLag: pause or fail")
+	flag.BoolVar(&driverMetrics, "driverMetrics", false, "Record command round trips and connection pool events of the driver in the per-second output")
+	flag.IntVar(&minPoolSize, "minPoolSize", 0, "Minimum number of connections in each client's pool")
+	flag.IntVar(&maxPoolSize, "maxPoolSize", 0, "Maximum number of connections in each client's pool (0 uses the number of threads)")
+	flag.IntVar(&maxConnecting, "maxConnecting", 0, "Maximum number of connections each pool establishes concurrently (0 uses the driver default)")
+	flag.IntVar(&maxIdleTime, "maxIdleTime", 0, "Seconds a pooled connection may stay idle before it is closed (0 keeps idle connections)")
+	flag.IntVar(&clients, "clients", 1, "Number of separate clients the threads are spread across, each with its own connection pool")
+	flag.StringVar(&pipelineFile, "pipelineFile", "", "Extended JSON file with the pipeline, or array of pipelines, for the aggregate test")
+	flag.BoolVar(&allowDiskUse, "allowDiskUse", false, "Allow aggregation stages to write temporary files to disk")
+	flag.IntVar(&batchSize, "batchSize", 0, "Cursor batch size of the aggregate and scan tests (0 uses the server default)")
+	flag.StringVar(&scanField, "scanField", "rnd", "Numeric field the scan test queries ranges of")
+	flag.Int64Var(&scanMin, "scanMin", 0, "Lowest value of -scanField in the collection")
+	flag.Int64Var(&scanMax, "scanMax", math.MaxInt64, "Highest value of -scanField in the collection")
+	flag.Int64Var(&scanWidth, "scanWidth", 0, "Width of the range each scan queries (0 scans 0.1% of the value range)")
+	flag.IntVar(&scanSort, "scanSort", 1, "Sort order of the scan on -scanField: 1, -1, or 0 for unsorted")
+	flag.StringVar(&scanProjection, "scanProjection", "", "Comma-separated fields the scan returns (empty returns whole documents)")
+	flag.Int64Var(&scanLimit, "scanLimit", 0, "Maximum number of documents per scan (0 for no limit)")
+	flag.StringVar(&indexFile, "indexFile", "", "Extended JSON file declaring the secondary indexes created before each test")
+	flag.BoolVar(&indexImpact, "indexImpact", false, "Run the test once per number of indexes from -indexFile, from 0 to all of them")
+	flag.StringVar(&indexBuildFile, "indexBuildFile", "", "Extended JSON file declaring indexes that are built while the test workload runs")
+	flag.IntVar(&indexBuildDelay, "indexBuildDelay", 10, "Seconds after the start of the test at which the -indexBuildFile indexes are built")
+	flag.IntVar(&watchers, "watchers", 0, "Number of change streams watching the collection while the test runs")
+	flag.StringVar(&watchPipeline, "watchPipelineFile", "", "Extended JSON file with the pipeline the change streams of -watchers apply")
+	flag.StringVar(&fullDocument, "fullDocument", "", "fullDocument option of the change streams, e.g. updateLookup (empty uses the server default)")
+	flag.BoolVar(&timeSeries, "timeSeries", false, "Create the collection as a time-series collection and insert sensor measurements")
+	flag.StringVar(&timeField, "timeField", "timestamp", "Time field of the time-series collection and the sensor measurements")
+	flag.StringVar(&metaField, "metaField", "sensor", "Metadata field of the time-series collection and the sensor measurements")
+	flag.StringVar(&granularity, "granularity", "", "Granularity of the time-series collection: seconds, minutes, or hours (empty uses the server default)")
+	flag.IntVar(&bucketMaxSpan, "bucketMaxSpan", 0, "Maximum time span in seconds of a time-series bucket, requires -bucketRounding (0 uses -granularity)")
+	flag.IntVar(&bucketRounding, "bucketRounding", 0, "Rounding in seconds of the start of a time-series bucket, requires -bucketMaxSpan")
+	flag.IntVar(&sensors, "sensors", 0, "Number of simulated sensors whose measurements the insert test writes (0 writes plain documents, 100 with -timeSeries)")
+	flag.IntVar(&sensorInterval, "sensorInterval", 10, "Seconds between two measurements of a simulated sensor")
+	flag.IntVar(&windowSize, "windowSize", 3600, "Time window in seconds each query of the window test covers")
+	flag.StringVar(&shardKey, "shardKey", "", "Shard the collection through mongos by this key, e.g. rnd:hashed or threadRunCount:1,rnd:1")
+	flag.IntVar(&shardChunks, "shardChunks", 0, "Number of chunks the sharded collection is pre-split into and distributed across the shards (0 leaves it to the balancer)")
+	flag.IntVar(&shardStats, "shardStatsInterval", 0, "Interval in seconds for sampling the per-shard distribution and balancer activity during the test (0 disables sampling)")
+	flag.IntVar(&queueItems, "queueItems", 100000, "Number of items enqueued for the queue test, at most the operation limit")
+	flag.IntVar(&thinkTime, "thinkTime", 0, "Milliseconds a worker of the queue test processes a claimed item before marking it done")
+	flag.IntVar(&buckets, "buckets", 0, "Number of buckets inserted documents are spread across, each updateMany or deleteMany statement affects one bucket (0 inserts no bucket field)")
+	flag.StringVar(&updateFile, "updateFile", "", "Extended JSON file with the weighted updates of the update, upsert, and updateMany tests (empty sets updatedAt and rnd)")
+	flag.IntVar(&replaceMinBytes, "replaceMinBytes", 0, "Minimum size in bytes of the payload each replacement of the replace test carries")
+	flag.IntVar(&replaceMaxBytes, "replaceMaxBytes", 0, "Maximum size in bytes of the payload each replacement of the replace test carries (0 adds no payload)")
+	flag.IntVar(&refillThreads, "refillThreads", 0, "Number of writers that keep inserting documents for the delete test, so it does not run out of documents")
+	flag.Int64Var(&maxOps, "maxOps", 0, "Stop a test after this many operations (defaults to -docs unless -duration is set)")
+	flag.Int64Var(&maxErrors, "maxErrors", 0, "Stop a test once this many operations failed (0 disables the error budget)")
+	flag.IntVar(&latencySLO, "latencySLO", 0, "Stop a test once the p99 latency exceeds this many milliseconds for -sloWindow seconds in a row (0 disables the SLO)")
+	flag.IntVar(&sloWindow, "sloWindow", 1, "Consecutive seconds above -latencySLO that stop a test")
+	flag.BoolVar(&dryRun, "dryRun", false, "Run the test against an in-memory collection instead of the server")
+	flag.IntVar(&dryRunLatency, "dryRunLatency", 0, "Milliseconds every operation of the in-memory collection takes with -dryRun")
+	flag.Float64Var(&dryRunFailures, "dryRunFailureRate", 0, "Share of the operations of the in-memory collection that fail with -dryRun, between 0 and 1")
+	flag.StringVar(&faultFile, "faultFile", "", "Extended JSON file declaring the latency spikes, errors, and stalls injected into the operations of the test")
+	flag.BoolVar(&verify, "verify", false, "Read back the written documents after each test and fail on lost or phantom writes")
+	flag.StringVar(&readConfigs, "readConfigs", "", "Comma-separated read concern:read preference pairs the staleReads and causalReads tests read with, e.g. majority:secondary,local:nearest")
+	flag.IntVar(&staleReadKeys, "staleReadKeys", 100, "Number of keys the staleReads test writes and reads")
+	flag.Float64Var(&readShare, "readShare", 0.8, "Share of the operations of the staleReads test that are reads, between 0 and 1")
+	flag.BoolVar(&failover, "failover", false, "Track primary changes and report the unavailability and recovery of each test, e.g. during stepdown drills")
+	flag.StringVar(&outputDir, "outputDir", "", "Directory the CSV files are saved to (empty uses the working directory)")
 	flag.Parse()
+	mongobench.SetLogger(log.Default())
 
-	var strategy TestingStrategy
-	var config TestingConfig
+	if replLagAction != string(mongobench.ReplicationLagPause) && replLagAction != string(mongobench.ReplicationLagFail) {
+		return fmt.Errorf("invalid -replLagAction %q, expected pause or fail", replLagAction)
+	}
+	if clients < 1 {
+		return fmt.Errorf("invalid -clients %d, at least one client is required", clients)
+	}
+	if indexImpact && (indexFile == "" || runAll) {
+		return fmt.Errorf("-indexImpact requires -indexFile and a single -type")
+	}
+	if dryRunFailures < 0 || dryRunFailures > 1 {
+		return fmt.Errorf("invalid -dryRunFailureRate %v, expected a share between 0 and 1", dryRunFailures)
+	}
+	if dryRun && (clients > 1 || watchers > 0 || shardKey != "" || shardStats > 0 || statsInterval > 0 || replLagInterval > 0 || failover || driverMetrics || driverRetryStats) {
+		return fmt.Errorf("-dryRun does not support -clients, -watchers, sharding, server statistics, replication lag monitoring, -failover, -driverMetrics, or -driverRetryStats")
+	}
+	if maxPoolSize == 0 {
+		maxPoolSize = threads
+	}
 
-	clientOptions := options.Client().ApplyURI(uri).SetMaxPoolSize(uint64(threads))
+	var config mongobench.TestingConfig
 
-	if certificatePath != "" {
-		tlsConfig, err := createTLSConfigFromFile(certificatePath)
-		if err != nil {
-			log.Fatalf("Failed to create TLS config from %s: %v", certificatePath, err)
+	var (
+		client          *mongo.Client
+		database        *mongo.Database
+		collection      *mongo.Collection
+		mongoCollection mongobench.CollectionAPI
+		monitoring      *mongobench.DriverMetrics
+		driverRetries   *mongobench.DriverRetries
+		failoverTracker *mongobench.FailoverTracker
+	)
+	if dryRun {
+		// No client is created, every operation goes to the in-memory collection
+		mongoCollection = mongobench.NewMemoryCollection(time.Duration(dryRunLatency)*time.Millisecond, dryRunFailures)
+	} else {
+		clientOptions := options.Client().ApplyURI(uri).
+			SetMaxPoolSize(uint64(maxPoolSize)).
+			SetMinPoolSize(uint64(minPoolSize)).
+			SetMaxConnIdleTime(time.Duration(maxIdleTime) * time.Second).
+			SetRetryWrites(retryWrites).
+			SetRetryReads(retryReads)
+		if maxConnecting > 0 {
+			clientOptions = clientOptions.SetMaxConnecting(uint64(maxConnecting))
 		}
 
-		clientOptions = clientOptions.SetTLSConfig(tlsConfig)
-	}
+		var commandMonitors []*event.CommandMonitor
+		if driverMetrics {
+			monitoring = mongobench.NewDriverMetrics()
+			commandMonitors = append(commandMonitors, monitoring.CommandMonitor())
+			clientOptions = clientOptions.SetPoolMonitor(monitoring.PoolMonitor())
+		}
+		if driverRetryStats {
+			driverRetries = mongobench.NewDriverRetries()
+			commandMonitors = append(commandMonitors, driverRetries.CommandMonitor())
+		}
+		if len(commandMonitors) > 0 {
+			clientOptions = clientOptions.SetMonitor(mongobench.CombineCommandMonitors(commandMonitors...))
+		}
+		if failover {
+			failoverTracker = mongobench.NewFailoverTracker()
+			clientOptions = clientOptions.SetServerMonitor(failoverTracker.ServerMonitor())
+		}
 
-	client, err := mongo.Connect(context.Background(), clientOptions)
-	if err != nil {
-		log.Fatalf("Failed to connect to MongoDB: %v", err)
-	}
-	defer func(client *mongo.Client, ctx context.Context) {
-		err := client.Disconnect(ctx)
-		if err != nil {
-			log.Fatalf("Failed to disconnect from MongoDB: %v", err)
+		if certificatePath != "" {
+			tlsConfig, err := createTLSConfigFromFile(certificatePath)
+			if err != nil {
+				return fmt.Errorf("failed to create TLS config from %s: %v", certificatePath, err)
+			}
+
+			clientOptions = clientOptions.SetTLSConfig(tlsConfig)
 		}
-	}(client, context.Background())
 
-	collection := client.Database("benchmarking").Collection("testdata")
-	mongoCollection := &MongoDBCollection{Collection: collection}
+		var connected []*mongo.Client
+		var collections []mongobench.CollectionAPI
+		for i := 0; i < clients; i++ {
+			client, err := mongo.Connect(context.Background(), clientOptions)
+			if err != nil {
+				return fmt.Errorf("failed to connect to MongoDB: %v", err)
+			}
+			defer func(client *mongo.Client, ctx context.Context) {
+				err := client.Disconnect(ctx)
+				if err != nil {
+					log.Printf("Failed to disconnect from MongoDB: %v", err)
+				}
+			}(client, context.Background())
+
+			connected = append(connected, client)
+			collections = append(collections, &mongobench.MongoDBCollection{Collection: client.Database("benchmarking").Collection("testdata")})
+		}
+
+		client = connected[0]
+		database = client.Database("benchmarking")
+		collection = database.Collection("testdata")
+		mongoCollection = collections[0]
+		if clients > 1 {
+			mongoCollection = mongobench.NewMultiClientCollection(collections)
+		}
+	}
 
-	config = TestingConfig{
+	config = mongobench.TestingConfig{
 		Threads:   threads,
 		Duration:  duration,
 		DocCount:  docCount,
 		LargeDocs: largeDocs,
 		DropDb:    dropDb,
+		Retry: mongobench.RetryPolicy{
+			MaxAttempts: retryAttempts,
+			Backoff:     time.Duration(retryBackoff) * time.Millisecond,
+			MaxBackoff:  time.Duration(retryMaxBackoff) * time.Millisecond,
+		},
+		PipelineFile: pipelineFile,
+		AllowDiskUse: allowDiskUse,
+		BatchSize:    int32(batchSize),
+
+		ScanField:      scanField,
+		ScanMin:        scanMin,
+		ScanMax:        scanMax,
+		ScanWidth:      scanWidth,
+		ScanSort:       scanSort,
+		ScanProjection: mongobench.SplitFields(scanProjection),
+		ScanLimit:      scanLimit,
+
+		Watchers:     watchers,
+		FullDocument: fullDocument,
+
+		QueueItems: queueItems,
+		ThinkTime:  time.Duration(thinkTime) * time.Millisecond,
+
+		Buckets: buckets,
+
+		ReplaceMinBytes: replaceMinBytes,
+		ReplaceMaxBytes: replaceMaxBytes,
+
+		Stop: mongobench.StopConditions{
+			MaxOps:      maxOps,
+			MaxDuration: time.Duration(duration) * time.Second,
+			MaxErrors:   maxErrors,
+			LatencySLO:  time.Duration(latencySLO) * time.Millisecond,
+			SLOWindow:   sloWindow,
+		},
+
+		RefillThreads: refillThreads,
+		Verify:        verify,
+		Failover:      failoverTracker,
+		DriverRetries: driverRetries,
+
+		StaleReadKeys: staleReadKeys,
+		ReadShare:     &readShare,
+
+		Output: mongobench.Output{Dir: outputDir},
+	}
+	if readConfigs != "" {
+		var err error
+		if config.ReadConfigs, err = mongobench.ParseReadConfigs(readConfigs); err != nil {
+			return fmt.Errorf("invalid read configurations: %v", err)
+		}
+	}
+	if updateFile != "" {
+		var err error
+		if config.Updates, err = mongobench.LoadUpdateMix(updateFile); err != nil {
+			return fmt.Errorf("failed to load updates: %v", err)
+		}
+	}
+	if indexFile != "" {
+		var err error
+		if config.Indexes, err = mongobench.LoadIndexes(indexFile); err != nil {
+			return fmt.Errorf("failed to load indexes: %v", err)
+		}
+	}
+	if indexBuildFile != "" {
+		var err error
+		if config.IndexBuild, err = mongobench.LoadIndexes(indexBuildFile); err != nil {
+			return fmt.Errorf("failed to load indexes to build: %v", err)
+		}
+		config.IndexBuildDelay = time.Duration(indexBuildDelay) * time.Second
+	}
+	if timeSeries {
+		config.TimeSeries = options.TimeSeries().SetTimeField(timeField).SetMetaField(metaField)
+		if granularity != "" {
+			config.TimeSeries.SetGranularity(granularity)
+		}
+		if bucketMaxSpan > 0 || bucketRounding > 0 {
+			config.TimeSeries.SetBucketMaxSpan(time.Duration(bucketMaxSpan) * time.Second)
+			config.TimeSeries.SetBucketRounding(time.Duration(bucketRounding) * time.Second)
+		}
+		if sensors == 0 {
+			sensors = 100
+		}
+	}
+	if sensors > 0 {
+		config.Sensors = mongobench.NewSensorFleet(sensors, time.Duration(sensorInterval)*time.Second, timeField, metaField)
+		config.WindowSize = time.Duration(windowSize) * time.Second
+	}
+	if shardKey != "" {
+		key, err := mongobench.ParseShardKey(shardKey)
+		if err != nil {
+			return fmt.Errorf("invalid shard key: %v", err)
+		}
+		namespace := database.Name() + "." + collection.Name()
+		if config.Sharding, err = mongobench.NewShardingSetup(client.Database("admin"), client.Database("config"), namespace, key, shardChunks); err != nil {
+			return fmt.Errorf("invalid sharding setup: %v", err)
+		}
+	}
+	if watchPipeline != "" {
+		var err error
+		if config.WatchPipeline, err = mongobench.LoadWatchPipeline(watchPipeline); err != nil {
+			return fmt.Errorf("failed to load watch pipeline: %v", err)
+		}
+	}
+	if monitoring != nil {
+		config.Metrics = append(config.Metrics, monitoring)
+	}
+	if failoverTracker != nil {
+		config.Metrics = append(config.Metrics, failoverTracker)
+	}
+	if faultFile != "" {
+		faults, err := mongobench.LoadFaults(faultFile)
+		if err != nil {
+			return fmt.Errorf("failed to load faults: %v", err)
+		}
+		injector := mongobench.NewFaultInjector(faults)
+		mongoCollection = mongobench.NewFaultyCollection(mongoCollection, injector)
+		config.Metrics = append(config.Metrics, injector)
 	}
 
-	if duration > 0 {
-		strategy = DurationTestingStrategy{}
-	} else {
-		strategy = DocCountTestingStrategy{}
+	if statsInterval > 0 {
+		sampler := mongobench.NewServerStatsSampler(database, collection.Name(), time.Duration(statsInterval)*time.Second)
+		sampler.Start()
+		defer func() {
+			if err := sampler.Stop(filepath.Join(outputDir, "server_stats.csv")); err != nil {
+				log.Printf("Failed to save server statistics: %v", err)
+			}
+		}()
+	}
+
+	if shardStats > 0 {
+		sampler := mongobench.NewShardStatsSampler(database, client.Database("admin"), client.Database("config"), database.Name(), collection.Name(), time.Duration(shardStats)*time.Second)
+		sampler.Start()
+		defer func() {
+			if err := sampler.Stop(filepath.Join(outputDir, "shard_stats.csv")); err != nil {
+				log.Printf("Failed to save shard statistics: %v", err)
+			}
+		}()
 	}
+
+	if replLagInterval > 0 {
+		config.Gate = mongobench.NewGate()
+		monitor := mongobench.NewReplicationLagMonitor(client.Database("admin"), time.Duration(replLagInterval)*time.Second,
+			time.Duration(maxReplLag)*time.Second, mongobench.ReplicationLagAction(replLagAction), config.Gate)
+		monitor.Start(filepath.Join(outputDir, "replication_lag.csv"))
+		defer func() {
+			if err := monitor.Stop(); err != nil {
+				log.Printf("Failed to save the replication lag: %v", err)
+			}
+		}()
+	}
+
+	if maxOps == 0 && duration == 0 {
+		config.Stop.MaxOps = int64(docCount)
+	}
+
+	var err error
+	runner := mongobench.Runner{}
+	var results []mongobench.TestResult
 	if runAll {
-		strategy.runTestSequence(mongoCollection, config)
+		results, err = runner.RunSequence(mongoCollection, config)
+	} else if indexImpact {
+		results, err = runner.RunIndexImpact(mongoCollection, testType, config)
 	} else {
-		strategy.runTest(mongoCollection, testType, config, fetchDocumentIDs)
+		var result mongobench.TestResult
+		result, err = runner.Run(mongoCollection, testType, config)
+		results = append(results, result)
+	}
+	if err != nil {
+		return fmt.Errorf("benchmark failed: %v", err)
+	}
+	for _, result := range results {
+		if result.Verification != nil && !result.Verification.Passed() {
+			return fmt.Errorf("verification of the %s test failed: %s", result.TestType, result.Verification)
+		}
 	}
+	return nil
 }
 
 func createTLSConfigFromFile(tlsCertificate string) (*tls.Config, error) {
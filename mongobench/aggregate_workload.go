@@ -0,0 +1,100 @@
+package mongobench
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	RegisterWorkload(WorkloadDefinition{Name: "aggregate", New: newQueryTest(func(collection CollectionAPI, config TestingConfig) (queryWorkload, error) {
+		workload, err := newAggregateWorkload(config)
+		if err != nil {
+			return nil, err
+		}
+		// The in-memory collection runs only a few stages, so the test fails before it starts instead of every run
+		if _, ok := collection.(*MemoryCollection); ok {
+			for _, pipeline := range workload.pipelines {
+				if _, err := toPipeline(pipeline.Generate(NewRandomizer())); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return workload, nil
+	})})
+}
+
+// aggregateWorkload runs user-supplied aggregation pipelines whose placeholders are filled by generators
+type aggregateWorkload struct {
+	pipelines []*Template
+	options   *options.AggregateOptions
+	stats     *QueryStats
+}
+
+func newAggregateWorkload(config TestingConfig) (*aggregateWorkload, error) {
+	if config.PipelineFile == "" {
+		return nil, fmt.Errorf("the aggregate test requires a pipeline file")
+	}
+	pipelines, err := loadPipelines(config.PipelineFile)
+	if err != nil {
+		return nil, err
+	}
+	opts := options.Aggregate().SetAllowDiskUse(config.AllowDiskUse)
+	if config.BatchSize > 0 {
+		opts.SetBatchSize(config.BatchSize)
+	}
+	return &aggregateWorkload{
+		pipelines: pipelines,
+		options:   opts,
+		stats:     NewQueryStats(),
+	}, nil
+}
+
+// loadPipelines reads an Extended JSON file holding either a single pipeline or an array of pipelines
+func loadPipelines(path string) ([]*Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pipeline file: %v", err)
+	}
+	var stages bson.A
+	if err := bson.UnmarshalExtJSON(data, false, &stages); err != nil {
+		return nil, fmt.Errorf("failed to parse pipeline file %s: %v", path, err)
+	}
+	if len(stages) == 0 {
+		return nil, fmt.Errorf("pipeline file %s is empty", path)
+	}
+
+	pipelines := []interface{}{stages}
+	if _, ok := stages[0].(bson.A); ok {
+		pipelines = stages
+	}
+
+	var templates []*Template
+	for _, pipeline := range pipelines {
+		template, err := CompileTemplate(pipeline)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pipeline in %s: %v", path, err)
+		}
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
+
+// run executes a randomly chosen pipeline and fully iterates its cursor
+func (w *aggregateWorkload) run(collection CollectionAPI, r *Randomizer) error {
+	pipeline := w.pipelines[r.RandomIntn(len(w.pipelines))].Generate(r)
+	start := time.Now()
+	cursor, err := collection.Aggregate(context.Background(), pipeline, w.options)
+	if err != nil {
+		return err
+	}
+	return w.stats.consume(cursor, start)
+}
+
+func (w *aggregateWorkload) queryStats() *QueryStats {
+	return w.stats
+}
@@ -0,0 +1,121 @@
+package mongobench
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func init() {
+	for _, testType := range []string{"updateMany", "deleteMany"} {
+		RegisterWorkload(WorkloadDefinition{Name: testType, New: func(env WorkloadEnv) (Workload, error) {
+			return newBulkWorkload(testType, env.Config, env.RetryStats)
+		}})
+	}
+}
+
+// bulkWorkload updates or deletes all documents of a bucket with a single statement. Inserted documents are spread
+// evenly across the buckets, so each statement affects docs/buckets documents on average. updateMany picks random
+// buckets, while deleteMany deletes the buckets one after the other.
+type bulkWorkload struct {
+	BaseWorkload
+	testType   string
+	buckets    int
+	config     TestingConfig
+	retryStats *RetryStats
+
+	nextBucket atomic.Int64
+	statements atomic.Int64
+	affected   atomic.Int64
+
+	mu               sync.Mutex
+	previousAffected int64
+	previousTime     time.Time
+}
+
+func newBulkWorkload(testType string, config TestingConfig, retryStats *RetryStats) (*bulkWorkload, error) {
+	if config.Buckets <= 0 {
+		return nil, fmt.Errorf("the %s test requires documents inserted with buckets", testType)
+	}
+	return &bulkWorkload{
+		testType:     testType,
+		buckets:      config.Buckets,
+		config:       config,
+		retryStats:   retryStats,
+		previousTime: time.Now(),
+	}, nil
+}
+
+// Setup creates the index on the bucket field the statements filter by
+func (w *bulkWorkload) Setup(collection CollectionAPI) error {
+	index := mongo.IndexModel{Keys: bson.D{{Key: "bucket", Value: 1}}}
+	if _, err := collection.CreateIndexes(context.Background(), []mongo.IndexModel{index}); err != nil {
+		return fmt.Errorf("failed to create the bucket index: %v", err)
+	}
+	return nil
+}
+
+// Run updates a random bucket or deletes the next one. Statements are retried by the workload itself, so a retried
+// deleteMany keeps its bucket.
+func (w *bulkWorkload) Run(collection CollectionAPI, worker *Worker) error {
+	r := worker.Random
+	var affected int64
+	var err error
+	if w.testType == "deleteMany" {
+		bucket := w.nextBucket.Add(1) - 1
+		if bucket >= int64(w.buckets) {
+			return ErrStopWorker // All buckets are deleted
+		}
+		err = w.config.Retry.run(w.retryStats, func() error {
+			result, err := collection.DeleteMany(context.Background(), bson.M{"bucket": bucket})
+			if err == nil {
+				affected = result.DeletedCount
+			}
+			return err
+		})
+	} else {
+		filter := bson.M{"bucket": r.RandomIntn(w.buckets)}
+		update, opts := newUpdate(r, w.config)
+		err = w.config.Retry.run(w.retryStats, func() error {
+			result, err := collection.UpdateMany(context.Background(), filter, update, opts)
+			if err == nil {
+				affected = result.ModifiedCount
+			}
+			return err
+		})
+	}
+	if err != nil {
+		return err
+	}
+	w.statements.Add(1)
+	w.affected.Add(affected)
+	return nil
+}
+
+// Columns implements MetricsSource
+func (w *bulkWorkload) Columns() []string {
+	return []string{"docs_affected", "docs_affected_per_sec", "docs_per_statement"}
+}
+
+// Values implements MetricsSource; the rate covers the interval since the previous call
+func (w *bulkWorkload) Values() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := time.Now()
+	affected := w.affected.Load()
+	rate := float64(affected-w.previousAffected) / now.Sub(w.previousTime).Seconds()
+	w.previousAffected = affected
+	w.previousTime = now
+
+	perStatement := 0.0
+	if statements := w.statements.Load(); statements > 0 {
+		perStatement = float64(affected) / float64(statements)
+	}
+	return []string{fmt.Sprintf("%d", affected), fmt.Sprintf("%.2f", rate), fmt.Sprintf("%.2f", perStatement)}
+}
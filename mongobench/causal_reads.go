@@ -0,0 +1,201 @@
+package mongobench
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	RegisterWorkload(WorkloadDefinition{Name: "causalReads", Fresh: true, New: newCausalReadWorkload})
+}
+
+// causalModes are the ways the causalReads test reads back its writes, in the order the workers alternate them
+var causalModes = []string{"causal", "plain"}
+
+// sessionCollections is implemented by collections whose client can start sessions
+type sessionCollections interface {
+	StartSession(opts ...*options.SessionOptions) (mongo.Session, error)
+}
+
+// causalReadStats measure the reads of one mode of the causalReads test
+type causalReadStats struct {
+	reads      atomic.Int64
+	violations atomic.Int64
+	latency    metrics.Histogram // since the previous row
+	total      metrics.Histogram // of the whole test
+}
+
+// causalReadWorker is the state of a worker of the causalReads test, only accessed by the worker itself
+type causalReadWorker struct {
+	reader  CollectionAPI
+	session mongo.Session
+	version int64
+	ops     int
+}
+
+// causalReadWorkload measures what causal consistency costs and whether it holds. Every worker writes the next
+// version of its own key and reads it back with the read configuration, alternating between reading inside a causally
+// consistent session and reading without one. A read that does not return the version just written violates
+// read-your-writes.
+type causalReadWorkload struct {
+	env     WorkloadEnv
+	config  ReadConfig
+	workers []*causalReadWorker
+	stats   []*causalReadStats
+
+	mu sync.Mutex
+}
+
+func newCausalReadWorkload(env WorkloadEnv) (Workload, error) {
+	config := ReadConfig{Concern: "majority", Preference: "secondary"}
+	switch len(env.Config.ReadConfigs) {
+	case 0:
+	case 1:
+		config = env.Config.ReadConfigs[0]
+	default:
+		return nil, fmt.Errorf("the causalReads test reads with a single read configuration, got %d", len(env.Config.ReadConfigs))
+	}
+	w := &causalReadWorkload{env: env, config: config, workers: make([]*causalReadWorker, env.Config.Threads)}
+	for i := range w.workers {
+		w.workers[i] = &causalReadWorker{}
+	}
+	for range causalModes {
+		w.stats = append(w.stats, &causalReadStats{
+			latency: metrics.NewHistogram(metrics.NewUniformSample(100000)),
+			total:   metrics.NewHistogram(metrics.NewUniformSample(100000)),
+		})
+	}
+	return w, nil
+}
+
+// Setup writes the first version of the key of every worker
+func (w *causalReadWorkload) Setup(collection CollectionAPI) error {
+	for key := range w.workers {
+		err := w.env.Config.Retry.run(w.env.RetryStats, func() error {
+			_, err := collection.ReplaceOne(context.Background(), bson.M{"_id": key}, bson.M{"_id": key, "version": int64(0)}, options.Replace().SetUpsert(true))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write key %d: %v", key, err)
+		}
+	}
+	logger.Printf("Reading back writes with %s, in causally consistent sessions and without", w.config)
+	return nil
+}
+
+// Run writes the next version of the worker's key and reads it back, in the worker's session every other time.
+// Collections without sessions, like the in-memory one, read without a session in both modes.
+func (w *causalReadWorkload) Run(collection CollectionAPI, worker *Worker) error {
+	state := w.workers[worker.ID]
+	if state.reader == nil {
+		reader, err := collectionWithRead(collection, w.config)
+		if err != nil {
+			return err
+		}
+		state.reader = reader
+		if sessions, ok := collection.(sessionCollections); ok {
+			if state.session, err = sessions.StartSession(options.Session().SetCausalConsistency(true)); err != nil {
+				return fmt.Errorf("failed to start a session: %v", err)
+			}
+		}
+	}
+
+	mode := state.ops % len(causalModes)
+	state.ops++
+	ctx := context.Background()
+	if causalModes[mode] == "causal" && state.session != nil {
+		ctx = mongo.NewSessionContext(ctx, state.session)
+	}
+
+	state.version++
+	version := state.version
+	err := w.env.Config.Retry.run(w.env.RetryStats, func() error {
+		_, err := collection.UpdateOne(ctx, bson.M{"_id": worker.ID}, bson.M{"$set": bson.M{"version": version}})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	var doc struct {
+		Version int64 `bson:"version"`
+	}
+	cursor, err := state.reader.Find(ctx, bson.M{"_id": worker.ID}, options.Find().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	if !cursor.Next(ctx) {
+		if err := cursor.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("key %d not found with %s", worker.ID, w.config)
+	}
+	if err := cursor.Decode(&doc); err != nil {
+		return err
+	}
+	stats := w.stats[mode]
+	stats.latency.Update(int64(time.Since(start)))
+	stats.total.Update(int64(time.Since(start)))
+	stats.reads.Add(1)
+	if doc.Version < version {
+		stats.violations.Add(1)
+	}
+	return nil
+}
+
+// Teardown ends the sessions and reports the latency causal consistency adds to the reads and the violations of
+// read-your-writes in both modes
+func (w *causalReadWorkload) Teardown(CollectionAPI) error {
+	for _, worker := range w.workers {
+		if worker.session != nil {
+			worker.session.EndSession(context.Background())
+		}
+	}
+	var means []float64
+	for mode, stats := range w.stats {
+		latency := stats.total.Snapshot()
+		means = append(means, latency.Mean())
+		logger.Printf("Reads %s: %d reads, latency mean %s ms, p99 %s ms, %d read-your-writes violations", causalModes[mode],
+			stats.reads.Load(), formatMillis(latency.Mean()), formatMillis(latency.Percentile(0.99)), stats.violations.Load())
+	}
+	logger.Printf("Causal consistency adds %s ms to the mean read latency", formatMillis(means[0]-means[1]))
+	return nil
+}
+
+// Columns implements MetricsSource
+func (w *causalReadWorkload) Columns() []string {
+	var columns []string
+	for _, mode := range causalModes {
+		columns = append(columns, mode+"_reads", mode+"_read_mean_ms", mode+"_read_p99_ms", mode+"_violations")
+	}
+	return columns
+}
+
+// Values implements MetricsSource; the latencies cover the interval since the previous call
+func (w *causalReadWorkload) Values() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var values []string
+	for _, stats := range w.stats {
+		latency := stats.latency.Snapshot()
+		stats.latency.Clear()
+		values = append(values,
+			fmt.Sprintf("%d", stats.reads.Load()),
+			formatMillis(latency.Mean()),
+			formatMillis(latency.Percentile(0.99)),
+			fmt.Sprintf("%d", stats.violations.Load()),
+		)
+	}
+	return values
+}
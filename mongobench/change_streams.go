@@ -0,0 +1,168 @@
+package mongobench
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// watcherDrainIdle is how long the watchers must have received no event after the writers finished
+	watcherDrainIdle = 1 * time.Second
+	// watcherDrainMax bounds the time spent waiting for outstanding events after the writers finished
+	watcherDrainMax = 10 * time.Second
+)
+
+// changeStreamWatchers consume change streams on the collection while the writers run. The end-to-end latency of
+// every event is measured from the writtenAt timestamp the writers embed in each inserted or updated document.
+type changeStreamWatchers struct {
+	pipeline bson.A
+	options  *options.ChangeStreamOptions
+	streams  []*mongo.ChangeStream
+	events   []atomic.Int64
+	lag      metrics.Histogram
+
+	lastEvent atomic.Int64
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+
+	mu             sync.Mutex
+	previousEvents []int64
+	previousTime   time.Time
+}
+
+func newChangeStreamWatchers(config TestingConfig) *changeStreamWatchers {
+	if config.Watchers <= 0 {
+		return nil
+	}
+	opts := options.ChangeStream()
+	if config.FullDocument != "" {
+		opts.SetFullDocument(options.FullDocument(config.FullDocument))
+	}
+	pipeline := config.WatchPipeline
+	if pipeline == nil {
+		pipeline = bson.A{}
+	}
+	return &changeStreamWatchers{
+		pipeline:       pipeline,
+		options:        opts,
+		events:         make([]atomic.Int64, config.Watchers),
+		lag:            metrics.NewHistogram(metrics.NewUniformSample(4096)),
+		previousEvents: make([]int64, config.Watchers),
+	}
+}
+
+// LoadWatchPipeline reads the change stream pipeline from an Extended JSON array of stages
+func LoadWatchPipeline(path string) (bson.A, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watch pipeline file: %v", err)
+	}
+	var pipeline bson.A
+	if err := bson.UnmarshalExtJSON(data, false, &pipeline); err != nil {
+		return nil, fmt.Errorf("failed to parse watch pipeline file %s: %v", path, err)
+	}
+	return pipeline, nil
+}
+
+// start opens all change streams before it returns, so no write of the test is missed, and consumes them in the
+// background
+func (w *changeStreamWatchers) start(collection CollectionAPI) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	for i := range w.events {
+		stream, err := collectionForWorker(collection, i).Watch(ctx, w.pipeline, w.options)
+		if err != nil {
+			cancel()
+			return fmt.Errorf("failed to open change stream: %v", err)
+		}
+		w.streams = append(w.streams, stream)
+	}
+
+	w.previousTime = time.Now()
+	for i, stream := range w.streams {
+		w.wg.Add(1)
+		go func(watcher int, stream *mongo.ChangeStream) {
+			defer w.wg.Done()
+			defer stream.Close(context.Background())
+			for stream.Next(ctx) {
+				w.received(watcher, stream.Current)
+			}
+			if err := stream.Err(); err != nil && ctx.Err() == nil {
+				logger.Printf("Change stream of watcher %d failed: %v", watcher, err)
+			}
+		}(i, stream)
+	}
+	return nil
+}
+
+func (w *changeStreamWatchers) received(watcher int, event bson.Raw) {
+	now := time.Now()
+	w.events[watcher].Add(1)
+	w.lastEvent.Store(now.UnixNano())
+	if writtenAt, ok := eventWrittenAt(event); ok {
+		w.lag.Update(now.UnixNano() - writtenAt)
+	}
+}
+
+// eventWrittenAt returns the write timestamp embedded in the document of an insert, update or replace event
+func eventWrittenAt(event bson.Raw) (int64, bool) {
+	if value, err := event.LookupErr("updateDescription", "updatedFields", "writtenAt"); err == nil {
+		return value.Int64OK()
+	}
+	if value, err := event.LookupErr("fullDocument", "writtenAt"); err == nil {
+		return value.Int64OK()
+	}
+	return 0, false
+}
+
+// stop waits until the events of the finished writers have been received, then closes the change streams
+func (w *changeStreamWatchers) stop() {
+	deadline := time.Now().Add(watcherDrainMax)
+	for time.Now().Before(deadline) && time.Since(time.Unix(0, w.lastEvent.Load())) < watcherDrainIdle {
+		time.Sleep(100 * time.Millisecond)
+	}
+	w.cancel()
+	w.wg.Wait()
+}
+
+// Columns implements MetricsSource
+func (w *changeStreamWatchers) Columns() []string {
+	columns := []string{"events", "event_lag_mean_ms", "event_lag_p99_ms"}
+	for i := range w.events {
+		columns = append(columns, fmt.Sprintf("watcher_%d_events_per_sec", i))
+	}
+	return columns
+}
+
+// Values implements MetricsSource; the lag and per-watcher rates cover the interval since the previous call
+func (w *changeStreamWatchers) Values() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(w.previousTime).Seconds()
+	w.previousTime = now
+	lag := w.lag.Snapshot()
+	w.lag.Clear()
+
+	var total int64
+	rates := make([]string, len(w.events))
+	for i := range w.events {
+		events := w.events[i].Load()
+		total += events
+		rates[i] = fmt.Sprintf("%.2f", float64(events-w.previousEvents[i])/elapsed)
+		w.previousEvents[i] = events
+	}
+
+	values := []string{fmt.Sprintf("%d", total), formatMillis(lag.Mean()), formatMillis(lag.Percentile(0.99))}
+	return append(values, rates...)
+}
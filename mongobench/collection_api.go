@@ -0,0 +1,224 @@
+package mongobench
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// CollectionAPI defines an interface for MongoDB operations, allowing for testing
+type CollectionAPI interface {
+	InsertOne(ctx context.Context, document interface{}) (*mongo.InsertOneResult, error)
+	InsertMany(ctx context.Context, documents []interface{}) (*mongo.InsertManyResult, error)
+	UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	ReplaceOne(ctx context.Context, filter interface{}, replacement interface{}, opts ...*options.ReplaceOptions) (*mongo.UpdateResult, error)
+	DeleteOne(ctx context.Context, filter interface{}) (*mongo.DeleteResult, error)
+	DeleteMany(ctx context.Context, filter interface{}) (*mongo.DeleteResult, error)
+	FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult
+	CountDocuments(ctx context.Context, filter interface{}) (int64, error)
+	Aggregate(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (*mongo.Cursor, error)
+	Drop(ctx context.Context) error
+	Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error)
+	CreateIndexes(ctx context.Context, models []mongo.IndexModel) ([]string, error)
+	DropIndexes(ctx context.Context) error
+	CreateCollection(ctx context.Context, opts ...*options.CreateCollectionOptions) error
+	Watch(ctx context.Context, pipeline interface{}, opts ...*options.ChangeStreamOptions) (*mongo.ChangeStream, error)
+}
+
+// MongoDBCollection is a wrapper around mongo.Collection to implement CollectionAPI
+type MongoDBCollection struct {
+	*mongo.Collection
+}
+
+func (c *MongoDBCollection) InsertOne(ctx context.Context, document interface{}) (*mongo.InsertOneResult, error) {
+	return c.Collection.InsertOne(ctx, document)
+}
+
+func (c *MongoDBCollection) InsertMany(ctx context.Context, documents []interface{}) (*mongo.InsertManyResult, error) {
+	return c.Collection.InsertMany(ctx, documents)
+}
+
+func (c *MongoDBCollection) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return c.Collection.UpdateOne(ctx, filter, update, opts...)
+}
+
+func (c *MongoDBCollection) UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return c.Collection.UpdateMany(ctx, filter, update, opts...)
+}
+
+func (c *MongoDBCollection) ReplaceOne(ctx context.Context, filter interface{}, replacement interface{}, opts ...*options.ReplaceOptions) (*mongo.UpdateResult, error) {
+	return c.Collection.ReplaceOne(ctx, filter, replacement, opts...)
+}
+
+func (c *MongoDBCollection) DeleteOne(ctx context.Context, filter interface{}) (*mongo.DeleteResult, error) {
+	return c.Collection.DeleteOne(ctx, filter)
+}
+
+func (c *MongoDBCollection) DeleteMany(ctx context.Context, filter interface{}) (*mongo.DeleteResult, error) {
+	return c.Collection.DeleteMany(ctx, filter)
+}
+
+func (c *MongoDBCollection) FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	return c.Collection.FindOneAndUpdate(ctx, filter, update, opts...)
+}
+
+func (c *MongoDBCollection) CountDocuments(ctx context.Context, filter interface{}) (int64, error) {
+	return c.Collection.CountDocuments(ctx, filter)
+}
+
+func (c *MongoDBCollection) Drop(ctx context.Context) error {
+	return c.Collection.Drop(ctx)
+}
+
+func (c *MongoDBCollection) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	return c.Collection.Find(ctx, filter, opts...)
+}
+
+func (c *MongoDBCollection) Aggregate(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (*mongo.Cursor, error) {
+	return c.Collection.Aggregate(ctx, pipeline, opts...)
+}
+
+func (c *MongoDBCollection) CreateIndexes(ctx context.Context, models []mongo.IndexModel) ([]string, error) {
+	return c.Collection.Indexes().CreateMany(ctx, models)
+}
+
+func (c *MongoDBCollection) DropIndexes(ctx context.Context) error {
+	_, err := c.Collection.Indexes().DropAll(ctx)
+	return err
+}
+
+func (c *MongoDBCollection) CreateCollection(ctx context.Context, opts ...*options.CreateCollectionOptions) error {
+	return c.Collection.Database().CreateCollection(ctx, c.Collection.Name(), opts...)
+}
+
+func (c *MongoDBCollection) Watch(ctx context.Context, pipeline interface{}, opts ...*options.ChangeStreamOptions) (*mongo.ChangeStream, error) {
+	return c.Collection.Watch(ctx, pipeline, opts...)
+}
+
+// WithRead returns a clone of the collection that reads with the given read concern and read preference
+func (c *MongoDBCollection) WithRead(concern *readconcern.ReadConcern, preference *readpref.ReadPref) (CollectionAPI, error) {
+	opts := options.Collection().SetReadPreference(preference)
+	if concern != nil {
+		opts.SetReadConcern(concern)
+	}
+	clone, err := c.Collection.Clone(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone the collection: %v", err)
+	}
+	return &MongoDBCollection{Collection: clone}, nil
+}
+
+// StartSession starts a session on the client of the collection
+func (c *MongoDBCollection) StartSession(opts ...*options.SessionOptions) (mongo.Session, error) {
+	return c.Collection.Database().Client().StartSession(opts...)
+}
+
+// MultiClientCollection spreads workers across the same collection opened through several clients, emulating many
+// application instances with their own connection pools. All other operations use the first client.
+type MultiClientCollection struct {
+	CollectionAPI
+	collections []CollectionAPI
+}
+
+func NewMultiClientCollection(collections []CollectionAPI) *MultiClientCollection {
+	return &MultiClientCollection{CollectionAPI: collections[0], collections: collections}
+}
+
+// ForWorker returns the collection of the client assigned to the given worker
+func (c *MultiClientCollection) ForWorker(worker int) CollectionAPI {
+	return c.collections[worker%len(c.collections)]
+}
+
+func fetchDocumentIDs(collection CollectionAPI, limit int64, testType string) ([]primitive.ObjectID, error) {
+	var docIDs []primitive.ObjectID
+	var cursor *mongo.Cursor
+	var err error
+
+	switch testType {
+	case "insert", "upsert", "delete":
+		if limit > 0 {
+			cursor, err = collection.Find(context.Background(), bson.M{}, options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(limit))
+		} else {
+			cursor, err = collection.Find(context.Background(), bson.M{}, options.Find().SetProjection(bson.M{"_id": 1}))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch document IDs: %v", err)
+		}
+		defer cursor.Close(context.Background())
+
+		for cursor.Next(context.Background()) {
+			var result bson.M
+			if err := cursor.Decode(&result); err != nil {
+				logger.Printf("Failed to decode document: %v", err)
+				continue
+			}
+			// Check if `_id` is of type `ObjectId` and add to `docIDs`
+			if id, ok := result["_id"].(primitive.ObjectID); ok {
+				docIDs = append(docIDs, id)
+			} else {
+				logger.Printf("Skipping document with unsupported _id type: %T", result["_id"])
+			}
+		}
+
+		if err := cursor.Err(); err != nil {
+			return nil, fmt.Errorf("cursor error: %v", err)
+		}
+	case "update", "replace":
+		if limit > 0 {
+			pipeline := []bson.M{{"$sample": bson.M{"size": limit}}}
+			cursor, err = collection.Aggregate(context.Background(), pipeline)
+			if err != nil {
+				return nil, fmt.Errorf("failed to aggregate documents: %v", err)
+			}
+			if cursor != nil {
+				defer cursor.Close(context.Background()) // Only defer if cursor is valid
+			}
+
+			for cursor.Next(context.Background()) {
+				var result bson.M
+				if err := cursor.Decode(&result); err != nil {
+					logger.Printf("Failed to decode document: %v", err)
+					continue
+				}
+				// Check if `_id` is of type `ObjectId` and add to `docIDs`
+				if id, ok := result["_id"].(primitive.ObjectID); ok {
+					docIDs = append(docIDs, id)
+				} else {
+					logger.Printf("Skipping document with unsupported _id type: %T", result["_id"])
+				}
+			}
+
+		} else {
+
+			cursor, err = collection.Find(context.Background(), bson.M{}, options.Find().SetProjection(bson.M{"_id": 1}))
+			if err != nil {
+				return nil, fmt.Errorf("failed to aggregate documents: %v", err)
+			}
+			if cursor != nil {
+				defer cursor.Close(context.Background()) // Only defer if cursor is valid
+			}
+
+			for cursor.Next(context.Background()) {
+				var result bson.M
+				if err := cursor.Decode(&result); err != nil {
+					logger.Printf("Failed to decode document: %v", err)
+					continue
+				}
+				// Check if `_id` is of type `ObjectId` and add to `docIDs`
+				if id, ok := result["_id"].(primitive.ObjectID); ok {
+					docIDs = append(docIDs, id)
+				} else {
+					logger.Printf("Skipping document with unsupported _id type: %T", result["_id"])
+				}
+			}
+		}
+	}
+
+	return docIDs, nil
+}
@@ -0,0 +1,174 @@
+package mongobench
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func init() {
+	RegisterWorkload(WorkloadDefinition{Name: "delete", New: newDeleteWorkload})
+}
+
+// deletePoolRefillBuffer is the number of refilled documents that may wait for a delete
+const deletePoolRefillBuffer = 1024
+
+// deletePool supplies the delete test with documents to delete. It starts with the IDs of existing
+// documents; refill writers keep inserting new documents into it, so a long run does not run out of documents.
+type deletePool struct {
+	ids           chan primitive.ObjectID
+	refillThreads int
+	refilled      atomic.Int64
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newDeletePool(ids []primitive.ObjectID, refillThreads int) (*deletePool, error) {
+	if len(ids) == 0 && refillThreads == 0 {
+		return nil, fmt.Errorf("no documents to delete, insert documents first or refill them with -refillThreads")
+	}
+	p := &deletePool{
+		ids:           make(chan primitive.ObjectID, len(ids)+deletePoolRefillBuffer),
+		refillThreads: refillThreads,
+		stop:          make(chan struct{}),
+	}
+	for _, id := range ids {
+		p.ids <- id
+	}
+	return p, nil
+}
+
+// startRefill starts the refill writers; their inserts are not counted as operations of the test
+func (p *deletePool) startRefill(collection CollectionAPI, config TestingConfig, data []byte, writes *WriteLog) {
+	for i := 0; i < p.refillThreads; i++ {
+		p.wg.Add(1)
+		go func(threadID int) {
+			defer p.wg.Done()
+			r := NewRandomizer()
+			for {
+				doc := newDocument(threadID, r, config, data)
+				id := primitive.NewObjectID()
+				doc["_id"] = id
+				_, err := collection.InsertOne(context.Background(), doc)
+				writes.Wrote(id, doc, err)
+				if err != nil {
+					logger.Printf("Refill insert failed: %v", err)
+				} else {
+					select {
+					case p.ids <- id:
+						p.refilled.Add(1)
+					case <-p.stop:
+						return
+					}
+				}
+				select {
+				case <-p.stop:
+					return
+				default:
+				}
+			}
+		}(config.Threads + i)
+	}
+}
+
+// next returns the ID of a document to delete. Without refill writers it reports false once the pool is empty,
+// with them it waits for a refilled document until the test is stopped.
+func (p *deletePool) next(stopped <-chan struct{}) (primitive.ObjectID, bool) {
+	if p.refillThreads == 0 {
+		select {
+		case id := <-p.ids:
+			return id, true
+		default:
+			return primitive.NilObjectID, false
+		}
+	}
+	select {
+	case id := <-p.ids:
+		return id, true
+	case <-stopped:
+		return primitive.NilObjectID, false
+	}
+}
+
+// finish stops the refill writers
+func (p *deletePool) finish() {
+	close(p.stop)
+	p.wg.Wait()
+}
+
+// Columns implements MetricsSource
+func (p *deletePool) Columns() []string {
+	return []string{"delete_pool", "refilled"}
+}
+
+// Values implements MetricsSource
+func (p *deletePool) Values() []string {
+	return []string{fmt.Sprintf("%d", len(p.ids)), fmt.Sprintf("%d", p.refilled.Load())}
+}
+
+// deleteWorkload deletes the documents of a deletePool one at a time
+type deleteWorkload struct {
+	env        WorkloadEnv
+	pool       *deletePool
+	collection CollectionAPI // the refill writers insert into, which is not subject to fault injection
+	refill     sync.Once
+}
+
+func newDeleteWorkload(env WorkloadEnv) (Workload, error) {
+	return &deleteWorkload{env: env}, nil
+}
+
+func (w *deleteWorkload) Setup(collection CollectionAPI) error {
+	docIDs, err := w.env.FetchDocIDs(collection, int64(w.env.Config.DocCount), "delete")
+	if err != nil {
+		return fmt.Errorf("failed to fetch document IDs: %v", err)
+	}
+	if w.pool, err = newDeletePool(docIDs, w.env.Config.RefillThreads); err != nil {
+		return err
+	}
+	w.collection = collection
+	return nil
+}
+
+// Run deletes the next document of the pool; the first call starts the refill writers, so they start with the workers
+func (w *deleteWorkload) Run(collection CollectionAPI, worker *Worker) error {
+	w.refill.Do(func() {
+		w.pool.startRefill(w.collection, w.env.Config, w.env.Data, w.env.Writes)
+	})
+	docID, ok := w.pool.next(worker.Stopped)
+	if !ok {
+		return ErrStopWorker
+	}
+	var result *mongo.DeleteResult
+	err := w.env.Config.Retry.run(w.env.RetryStats, func() error {
+		var err error
+		result, err = collection.DeleteOne(context.Background(), bson.M{"_id": docID})
+		return err
+	})
+	w.env.Writes.Deleted(docID, err)
+	if err == nil && result.DeletedCount == 0 {
+		return ErrNoEffect // Deleted by someone else, nothing to count
+	}
+	return err
+}
+
+func (w *deleteWorkload) Teardown(CollectionAPI) error {
+	w.pool.finish()
+	return nil
+}
+
+// Columns implements MetricsSource
+func (w *deleteWorkload) Columns() []string {
+	return w.pool.Columns()
+}
+
+// Values implements MetricsSource
+func (w *deleteWorkload) Values() []string {
+	return w.pool.Values()
+}
@@ -0,0 +1,45 @@
+package mongobench
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// newDocument generates a document for the insert test
+func newDocument(threadID int, r *Randomizer, config TestingConfig, data []byte) bson.M {
+	var doc bson.M
+	if config.Sensors != nil {
+		doc = config.Sensors.measurement(r)
+	} else {
+		doc = bson.M{"threadRunCount": threadID, "rnd": r.RandomInt63(), "v": 1}
+	}
+	if config.Buckets > 0 {
+		doc["bucket"] = r.RandomIntn(config.Buckets)
+	}
+	if config.LargeDocs {
+		doc["data"] = data
+	}
+	if config.Watchers > 0 {
+		doc["writtenAt"] = time.Now().UnixNano()
+	}
+	return doc
+}
+
+// newUpdate generates the update of the update, upsert and updateMany tests, picked from the update mix if one is
+// configured
+func newUpdate(r *Randomizer, config TestingConfig) (interface{}, *options.UpdateOptions) {
+	if config.Updates != nil {
+		var set bson.D
+		if config.Watchers > 0 {
+			set = bson.D{{Key: "writtenAt", Value: time.Now().UnixNano()}}
+		}
+		return config.Updates.next(r, set)
+	}
+	set := bson.M{"updatedAt": time.Now().Unix(), "rnd": r.RandomInt63()}
+	if config.Watchers > 0 {
+		set["writtenAt"] = time.Now().UnixNano()
+	}
+	return bson.M{"$set": set}, options.Update()
+}
@@ -0,0 +1,135 @@
+package mongobench
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// DriverMetrics records command round-trip times and connection pool events as seen by the driver. Latencies are
+// reported per interval between two samples, counters cumulatively.
+type DriverMetrics struct {
+	commandRTT         metrics.Histogram
+	checkoutWait       metrics.Histogram
+	commands           metrics.Counter
+	commandsFailed     metrics.Counter
+	connectionsCreated metrics.Counter
+	connectionsClosed  metrics.Counter
+	checkoutsFailed    metrics.Counter
+	poolCleared        metrics.Counter
+}
+
+func NewDriverMetrics() *DriverMetrics {
+	return &DriverMetrics{
+		commandRTT:         metrics.NewHistogram(metrics.NewUniformSample(4096)),
+		checkoutWait:       metrics.NewHistogram(metrics.NewUniformSample(4096)),
+		commands:           metrics.NewCounter(),
+		commandsFailed:     metrics.NewCounter(),
+		connectionsCreated: metrics.NewCounter(),
+		connectionsClosed:  metrics.NewCounter(),
+		checkoutsFailed:    metrics.NewCounter(),
+		poolCleared:        metrics.NewCounter(),
+	}
+}
+
+// CommandMonitor returns the monitor to register with the client options
+func (d *DriverMetrics) CommandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			d.commands.Inc(1)
+			d.commandRTT.Update(int64(e.Duration))
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			d.commands.Inc(1)
+			d.commandsFailed.Inc(1)
+			d.commandRTT.Update(int64(e.Duration))
+		},
+	}
+}
+
+// CombineCommandMonitors returns a command monitor passing every event to all of the given monitors, as a client takes
+// a single one
+func CombineCommandMonitors(monitors ...*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			for _, monitor := range monitors {
+				if monitor.Started != nil {
+					monitor.Started(ctx, e)
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			for _, monitor := range monitors {
+				if monitor.Succeeded != nil {
+					monitor.Succeeded(ctx, e)
+				}
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			for _, monitor := range monitors {
+				if monitor.Failed != nil {
+					monitor.Failed(ctx, e)
+				}
+			}
+		},
+	}
+}
+
+// PoolMonitor returns the monitor to register with the client options
+func (d *DriverMetrics) PoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			switch e.Type {
+			case event.GetSucceeded:
+				d.checkoutWait.Update(int64(e.Duration))
+			case event.GetFailed:
+				d.checkoutsFailed.Inc(1)
+				d.checkoutWait.Update(int64(e.Duration))
+			case event.ConnectionCreated:
+				d.connectionsCreated.Inc(1)
+			case event.ConnectionClosed:
+				d.connectionsClosed.Inc(1)
+			case event.PoolCleared:
+				d.poolCleared.Inc(1)
+			}
+		},
+	}
+}
+
+// Columns implements MetricsSource
+func (d *DriverMetrics) Columns() []string {
+	return []string{
+		"commands", "commands_failed", "cmd_rtt_mean_ms", "cmd_rtt_p99_ms",
+		"checkout_wait_mean_ms", "checkout_wait_p99_ms", "checkouts_failed",
+		"conns_created", "conns_closed", "pool_cleared",
+	}
+}
+
+// Values implements MetricsSource; the latency histograms are reset after every call
+func (d *DriverMetrics) Values() []string {
+	rtt := d.commandRTT.Snapshot()
+	d.commandRTT.Clear()
+	wait := d.checkoutWait.Snapshot()
+	d.checkoutWait.Clear()
+
+	return []string{
+		fmt.Sprintf("%d", d.commands.Count()),
+		fmt.Sprintf("%d", d.commandsFailed.Count()),
+		formatMillis(rtt.Mean()),
+		formatMillis(rtt.Percentile(0.99)),
+		formatMillis(wait.Mean()),
+		formatMillis(wait.Percentile(0.99)),
+		fmt.Sprintf("%d", d.checkoutsFailed.Count()),
+		fmt.Sprintf("%d", d.connectionsCreated.Count()),
+		fmt.Sprintf("%d", d.connectionsClosed.Count()),
+		fmt.Sprintf("%d", d.poolCleared.Count()),
+	}
+}
+
+// formatMillis formats a duration given in nanoseconds as milliseconds
+func formatMillis(nanos float64) string {
+	return fmt.Sprintf("%.3f", nanos/float64(time.Millisecond))
+}
@@ -0,0 +1,309 @@
+package mongobench
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/description"
+)
+
+const (
+	// failoverBaseline is how long before an outage the throughput to recover to is measured
+	failoverBaseline = 10 * time.Second
+	// failoverRecoveryShare is the share of the throughput before an outage that counts as recovered
+	failoverRecoveryShare = 0.9
+)
+
+// FailoverEvent is an entry of the failover timeline
+type FailoverEvent struct {
+	Time  time.Time
+	Event string
+}
+
+// FailoverReport summarizes the availability of a test that saw the primary change
+type FailoverReport struct {
+	PrimaryChanges int
+	OutageStart    time.Time     // last successful operation before the outage
+	Unavailable    time.Duration // longest time without a successful operation
+	FailedOps      int64         // operations that failed during the test
+	Baseline       float64       // successful operations per second before the outage
+	Recovered      bool
+	TimeToRecover  time.Duration // from the start of the outage until the throughput was back to the baseline
+}
+
+func (r FailoverReport) String() string {
+	report := fmt.Sprintf("%d primary changes, operations unavailable for %.3f s from %s, %d failed operations",
+		r.PrimaryChanges, r.Unavailable.Seconds(), r.OutageStart.Format("15:04:05.000"), r.FailedOps)
+	switch {
+	case r.Baseline == 0:
+		return report + ", no throughput before the outage to recover to"
+	case r.Recovered:
+		return report + fmt.Sprintf(", recovered to %.0f%% of %.2f ops/sec after %.3f s", failoverRecoveryShare*100, r.Baseline, r.TimeToRecover.Seconds())
+	default:
+		return report + fmt.Sprintf(", not recovered to %.0f%% of %.2f ops/sec before the end of the test", failoverRecoveryShare*100, r.Baseline)
+	}
+}
+
+// FailoverTracker follows the server descriptions the drivers see, like primary changes, and the outcome of every
+// operation, so that a failover during a test can be reported with its timeline, unavailability and recovery. The
+// clients sharing its ServerMonitor report the same changes, so every change of the primary or of the kind of a
+// server is recorded once. A nil FailoverTracker tracks nothing.
+type FailoverTracker struct {
+	mu        sync.Mutex
+	primary   string                        // primary of the replica set, as last reported by any client
+	primaries map[primitive.ObjectID]string // primary every client sees, by topology
+	kinds     map[string]string             // kind of every server, as last reported by any client
+	timeline  []FailoverEvent
+	pending   []string // events since the previous row
+
+	started     time.Time
+	workers     []*failoverWorker
+	lastSuccess atomic.Int64 // Unix nanoseconds of the latest successful operation
+	longestGap  atomic.Int64 // nanoseconds without a successful operation, the longest so far
+	outageStart time.Time    // start and end of the longest gap, guarded by mu
+	outageEnd   time.Time
+}
+
+// failoverWorker counts the operations of a worker; it is only accessed by the worker until the test finished
+type failoverWorker struct {
+	perSecond []int64 // successful operations per second since the start of the test
+	failed    int64
+}
+
+func NewFailoverTracker() *FailoverTracker {
+	return &FailoverTracker{
+		primary:   "none",
+		primaries: make(map[primitive.ObjectID]string),
+		kinds:     make(map[string]string),
+	}
+}
+
+// ServerMonitor returns the monitor to register with the client options, which may be shared by several clients
+func (f *FailoverTracker) ServerMonitor() *event.ServerMonitor {
+	return &event.ServerMonitor{
+		ServerDescriptionChanged: func(e *event.ServerDescriptionChangedEvent) {
+			f.serverChanged(e.Address.String(), serverKind(e.PreviousDescription), serverKind(e.NewDescription))
+		},
+		TopologyDescriptionChanged: func(e *event.TopologyDescriptionChangedEvent) {
+			primary := "none"
+			for _, server := range e.NewDescription.Servers {
+				if server.Kind == description.RSPrimary {
+					primary = server.Addr.String()
+				}
+			}
+			f.topologyChanged(e.TopologyID, primary)
+		},
+	}
+}
+
+func serverKind(server description.Server) string {
+	if server.Kind == 0 {
+		return "Unknown"
+	}
+	return server.Kind.String()
+}
+
+// serverChanged records a new kind of a server, unless another client reported it already
+func (f *FailoverTracker) serverChanged(addr, previous, kind string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if known, ok := f.kinds[addr]; ok {
+		previous = known
+	}
+	f.kinds[addr] = kind
+	if kind != previous {
+		f.record(fmt.Sprintf("%s changed from %s to %s", addr, previous, kind))
+	}
+}
+
+// topologyChanged records the primary a client sees. The primary of the replica set only changes when a client loses
+// the current primary or sees a different one, so the clients following the same change record it once.
+func (f *FailoverTracker) topologyChanged(topology primitive.ObjectID, primary string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	previous, ok := f.primaries[topology]
+	f.primaries[topology] = primary
+	if ok && primary == previous {
+		return
+	}
+	if primary == f.primary || (primary == "none" && previous != f.primary) {
+		return
+	}
+	f.record(fmt.Sprintf("primary changed from %s to %s", f.primary, primary))
+	f.primary = primary
+}
+
+// record adds an event to the timeline; the caller holds mu
+func (f *FailoverTracker) record(event string) {
+	f.timeline = append(f.timeline, FailoverEvent{Time: time.Now(), Event: event})
+	f.pending = append(f.pending, event)
+}
+
+// begin resets the operations tracked for the previous test
+func (f *FailoverTracker) begin(workers int) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.started = time.Now()
+	f.workers = make([]*failoverWorker, workers)
+	for i := range f.workers {
+		f.workers[i] = &failoverWorker{}
+	}
+	f.lastSuccess.Store(0)
+	f.longestGap.Store(0)
+	f.outageStart, f.outageEnd = time.Time{}, time.Time{}
+}
+
+// observe records the outcome of an operation of a worker. Only an operation following the longest gap so far takes
+// the lock, every other one updates the counters of its worker and the time of the latest success.
+func (f *FailoverTracker) observe(worker int, succeeded bool) {
+	if f == nil {
+		return
+	}
+	state := f.workers[worker]
+	if !succeeded {
+		state.failed++
+		return
+	}
+	now := time.Now()
+	second := int(now.Sub(f.started) / time.Second)
+	for len(state.perSecond) <= second {
+		state.perSecond = append(state.perSecond, 0)
+	}
+	state.perSecond[second]++
+
+	nanos := now.UnixNano()
+	if previous := f.lastSuccess.Swap(nanos); previous != 0 && nanos-previous > f.longestGap.Load() {
+		f.mu.Lock()
+		if nanos-previous > f.longestGap.Load() {
+			f.longestGap.Store(nanos - previous)
+			f.outageStart, f.outageEnd = time.Unix(0, previous), now
+		}
+		f.mu.Unlock()
+	}
+}
+
+// finish reports the failover of the test and saves its timeline; it returns no report if the primary did not
+// change. Call it after all workers finished.
+func (f *FailoverTracker) finish(testType string, config TestingConfig) (*FailoverReport, error) {
+	if f == nil {
+		return nil, nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ended := time.Now()
+
+	var perSecond []int64
+	var failed int64
+	for _, worker := range f.workers {
+		failed += worker.failed
+		for second, successes := range worker.perSecond {
+			for len(perSecond) <= second {
+				perSecond = append(perSecond, 0)
+			}
+			perSecond[second] += successes
+		}
+	}
+	successes := func(second int) int64 {
+		if second < 0 || second >= len(perSecond) {
+			return 0
+		}
+		return perSecond[second]
+	}
+
+	var events []FailoverEvent
+	report := FailoverReport{FailedOps: failed}
+	for _, e := range f.timeline {
+		if e.Time.Before(f.started) {
+			continue
+		}
+		events = append(events, e)
+		if strings.HasPrefix(e.Event, "primary changed") {
+			report.PrimaryChanges++
+		}
+	}
+	if report.PrimaryChanges == 0 {
+		logger.Printf("Failover: the primary did not change during the %s test, %d failed operations", testType, failed)
+		return nil, nil
+	}
+
+	var lastSuccess time.Time
+	if nanos := f.lastSuccess.Load(); nanos != 0 {
+		lastSuccess = time.Unix(0, nanos)
+	}
+	outageStart, outageEnd := f.outageStart, f.outageEnd
+	if lastSuccess.IsZero() || ended.Sub(lastSuccess) > outageEnd.Sub(outageStart) {
+		// Still unavailable at the end of the test
+		outageStart, outageEnd = lastSuccess, time.Time{}
+		if outageStart.IsZero() {
+			outageStart = f.started
+		}
+	}
+	report.OutageStart = outageStart
+	if outageEnd.IsZero() {
+		report.Unavailable = ended.Sub(outageStart)
+	} else {
+		report.Unavailable = outageEnd.Sub(outageStart)
+	}
+
+	// The second the outage started in is partial, so it is left out of the baseline
+	outageSecond := int(outageStart.Sub(f.started) / time.Second)
+	var seconds, baseline int64
+	for second := max(outageSecond-int(failoverBaseline/time.Second), 0); second < outageSecond; second++ {
+		seconds++
+		baseline += successes(second)
+	}
+	if seconds > 0 {
+		report.Baseline = float64(baseline) / float64(seconds)
+	}
+	if report.Baseline > 0 && !outageEnd.IsZero() {
+		for second := int(outageEnd.Sub(f.started) / time.Second); second < int(ended.Sub(f.started)/time.Second); second++ {
+			if float64(successes(second)) >= report.Baseline*failoverRecoveryShare {
+				report.Recovered = true
+				report.TimeToRecover = f.started.Add(time.Duration(second+1) * time.Second).Sub(outageStart)
+				break
+			}
+		}
+	}
+	logger.Printf("Failover during the %s test: %s", testType, report)
+
+	events = append(events, FailoverEvent{Time: outageStart, Event: "last successful operation before the outage"})
+	if !outageEnd.IsZero() {
+		events = append(events, FailoverEvent{Time: outageEnd, Event: "first successful operation after the outage"})
+	}
+	if report.Recovered {
+		events = append(events, FailoverEvent{Time: outageStart.Add(report.TimeToRecover), Event: "throughput recovered"})
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	records := [][]string{{"t", "elapsed_s", "event"}}
+	for _, e := range events {
+		records = append(records, []string{fmt.Sprintf("%d", e.Time.Unix()), fmt.Sprintf("%.3f", e.Time.Sub(f.started).Seconds()), e.Event})
+	}
+	filename := fmt.Sprintf("failover_%s.csv", testType)
+	if config.RunLabel != "" {
+		filename = fmt.Sprintf("failover_%s_%s.csv", testType, config.RunLabel)
+	}
+	return &report, config.Output.save("Failover timeline", filename, records)
+}
+
+// Columns implements MetricsSource
+func (f *FailoverTracker) Columns() []string {
+	return []string{"primary", "topology_events"}
+}
+
+// Values implements MetricsSource; the topology events are the ones since the previous call
+func (f *FailoverTracker) Values() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	events := strings.Join(f.pending, " | ")
+	f.pending = nil
+	return []string{f.primary, events}
+}
@@ -0,0 +1,318 @@
+package mongobench
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// faultErrors are the errors a fault can inject by name, shaped like the errors the server and the driver return
+var faultErrors = map[string]mongo.CommandError{
+	"NotWritablePrimary": {Code: 10107, Name: "NotWritablePrimary", Message: "not primary", Labels: []string{"RetryableWriteError"}},
+	"WriteConflict":      {Code: 112, Name: "WriteConflict", Message: "write conflict", Labels: []string{"TransientTransactionError"}},
+	"ExceededTimeLimit":  {Code: 262, Name: "ExceededTimeLimit", Message: "operation exceeded time limit"},
+	"network":            {Name: "NetworkError", Message: "connection reset by peer", Labels: []string{"NetworkError", "RetryableWriteError"}},
+}
+
+// faultFile is the declaration of a fault in the fault file
+type faultFile struct {
+	Kind        string   `bson:"kind"`
+	Probability *float64 `bson:"probability"`
+	EverySec    float64  `bson:"everySec"`
+	ForSec      float64  `bson:"forSec"`
+	LatencyMs   int      `bson:"latencyMs"`
+	Error       string   `bson:"error"`
+	Code        int32    `bson:"code"`
+}
+
+// Fault misbehaves like a database would: it delays operations (latency), fails them (error) or holds them until the
+// fault is over (stall). A fault with a schedule is active for For at the end of every Every, otherwise it is active
+// all the time. While active, it hits each operation with the given probability.
+type Fault struct {
+	Kind        string
+	Probability float64
+	Every       time.Duration
+	For         time.Duration
+	Latency     time.Duration
+	Err         error
+}
+
+// LoadFaults reads the faults of an Extended JSON file
+func LoadFaults(path string) ([]Fault, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fault file: %v", err)
+	}
+	var files []faultFile
+	if err := bson.UnmarshalExtJSON(data, false, &files); err != nil {
+		return nil, fmt.Errorf("failed to parse fault file %s: %v", path, err)
+	}
+
+	var faults []Fault
+	for i, file := range files {
+		fault := Fault{
+			Kind:        file.Kind,
+			Probability: 1,
+			Every:       time.Duration(file.EverySec * float64(time.Second)),
+			For:         time.Duration(file.ForSec * float64(time.Second)),
+			Latency:     time.Duration(file.LatencyMs) * time.Millisecond,
+		}
+		if file.Probability != nil {
+			fault.Probability = *file.Probability
+		}
+		if fault.Probability <= 0 || fault.Probability > 1 {
+			return nil, fmt.Errorf("fault %d in %s has a probability outside of (0, 1]", i, path)
+		}
+		if (fault.Every > 0) != (fault.For > 0) || fault.For > fault.Every {
+			return nil, fmt.Errorf("fault %d in %s needs both everySec and forSec, with forSec not above everySec", i, path)
+		}
+		switch file.Kind {
+		case "latency":
+			if fault.Latency <= 0 {
+				return nil, fmt.Errorf("latency fault %d in %s has no latencyMs", i, path)
+			}
+		case "stall":
+			if fault.Every == 0 {
+				return nil, fmt.Errorf("stall fault %d in %s has no schedule", i, path)
+			}
+		case "error":
+			if fault.Err, err = faultError(file.Error, file.Code); err != nil {
+				return nil, fmt.Errorf("error fault %d in %s: %v", i, path, err)
+			}
+		default:
+			return nil, fmt.Errorf("fault %d in %s has the unknown kind %q, expected latency, error or stall", i, path, file.Kind)
+		}
+		faults = append(faults, fault)
+	}
+	return faults, nil
+}
+
+// faultError returns the named error, or a command error with the given code
+func faultError(name string, code int32) (error, error) {
+	if err, ok := faultErrors[name]; ok {
+		return err, nil
+	}
+	if code != 0 {
+		if name == "" {
+			name = fmt.Sprintf("Error%d", code)
+		}
+		return mongo.CommandError{Code: code, Name: name, Message: "injected " + name}, nil
+	}
+	return nil, fmt.Errorf("unknown error %q, expected NotWritablePrimary, WriteConflict, ExceededTimeLimit, network, or a code", name)
+}
+
+// window reports whether the fault is active after the elapsed time, and for how much longer
+func (f Fault) window(elapsed time.Duration) (bool, time.Duration) {
+	if f.Every == 0 {
+		return true, 0
+	}
+	if elapsed < f.Every-f.For {
+		return false, 0
+	}
+	offset := (elapsed + f.For) % f.Every
+	if offset < f.For {
+		return true, f.For - offset
+	}
+	return false, 0
+}
+
+// FaultInjector applies the faults to the operations of FaultyCollections and counts the faults it injected. The
+// schedules of the faults are measured from the start of the test.
+type FaultInjector struct {
+	faults  []Fault
+	started atomic.Int64 // Unix nanoseconds the schedules start from
+
+	mu     sync.Mutex
+	random *Randomizer
+
+	delayed atomic.Int64
+	failed  atomic.Int64
+	stalled atomic.Int64
+}
+
+func NewFaultInjector(faults []Fault) *FaultInjector {
+	i := &FaultInjector{faults: faults, random: NewRandomizer()}
+	i.restart()
+	return i
+}
+
+// restart starts the schedules of the faults over, the runner calls it when a test starts
+func (i *FaultInjector) restart() {
+	i.started.Store(time.Now().UnixNano())
+}
+
+// inject applies the active faults to an operation and returns the error the operation fails with, if any
+func (i *FaultInjector) inject() error {
+	elapsed := time.Since(time.Unix(0, i.started.Load()))
+	for _, fault := range i.faults {
+		active, remaining := fault.window(elapsed)
+		if !active || !i.hits(fault.Probability) {
+			continue
+		}
+		switch fault.Kind {
+		case "latency":
+			i.delayed.Add(1)
+			time.Sleep(fault.Latency)
+		case "stall":
+			i.stalled.Add(1)
+			time.Sleep(remaining)
+		case "error":
+			i.failed.Add(1)
+			return fault.Err
+		}
+	}
+	return nil
+}
+
+func (i *FaultInjector) hits(probability float64) bool {
+	if probability >= 1 {
+		return true
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.random.RandomFloat64() < probability
+}
+
+// Columns implements MetricsSource
+func (i *FaultInjector) Columns() []string {
+	return []string{"faults_latency", "faults_error", "faults_stall"}
+}
+
+// Values implements MetricsSource
+func (i *FaultInjector) Values() []string {
+	return []string{fmt.Sprintf("%d", i.delayed.Load()), fmt.Sprintf("%d", i.failed.Load()), fmt.Sprintf("%d", i.stalled.Load())}
+}
+
+// FaultyCollection decorates a collection with injected faults. The runner only passes it to the workers, so the
+// operations of the workloads are affected, while preparing the collection, the setup and teardown of the workloads,
+// and the verification of the writes run against the decorated collection.
+type FaultyCollection struct {
+	CollectionAPI
+	injector *FaultInjector
+}
+
+func NewFaultyCollection(collection CollectionAPI, injector *FaultInjector) *FaultyCollection {
+	return &FaultyCollection{CollectionAPI: collection, injector: injector}
+}
+
+// undecorated returns the collection a FaultyCollection decorates, and any other collection as it is
+func undecorated(collection CollectionAPI) CollectionAPI {
+	if faulty, ok := collection.(*FaultyCollection); ok {
+		return faulty.CollectionAPI
+	}
+	return collection
+}
+
+// ForWorker keeps injecting faults into the collections of workers with their own client
+func (c *FaultyCollection) ForWorker(worker int) CollectionAPI {
+	return &FaultyCollection{CollectionAPI: collectionForWorker(c.CollectionAPI, worker), injector: c.injector}
+}
+
+// WithRead keeps injecting faults into the reads with another read concern and read preference
+func (c *FaultyCollection) WithRead(concern *readconcern.ReadConcern, preference *readpref.ReadPref) (CollectionAPI, error) {
+	provider, ok := c.CollectionAPI.(readCollections)
+	if !ok {
+		return c, nil
+	}
+	collection, err := provider.WithRead(concern, preference)
+	if err != nil {
+		return nil, err
+	}
+	return &FaultyCollection{CollectionAPI: collection, injector: c.injector}, nil
+}
+
+// StartSession starts a session on the decorated collection, or returns no session if it does not support them;
+// starting sessions is not affected by faults
+func (c *FaultyCollection) StartSession(opts ...*options.SessionOptions) (mongo.Session, error) {
+	sessions, ok := c.CollectionAPI.(sessionCollections)
+	if !ok {
+		return nil, nil
+	}
+	return sessions.StartSession(opts...)
+}
+
+func (c *FaultyCollection) InsertOne(ctx context.Context, document interface{}) (*mongo.InsertOneResult, error) {
+	if err := c.injector.inject(); err != nil {
+		return nil, err
+	}
+	return c.CollectionAPI.InsertOne(ctx, document)
+}
+
+func (c *FaultyCollection) InsertMany(ctx context.Context, documents []interface{}) (*mongo.InsertManyResult, error) {
+	if err := c.injector.inject(); err != nil {
+		return nil, err
+	}
+	return c.CollectionAPI.InsertMany(ctx, documents)
+}
+
+func (c *FaultyCollection) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	if err := c.injector.inject(); err != nil {
+		return nil, err
+	}
+	return c.CollectionAPI.UpdateOne(ctx, filter, update, opts...)
+}
+
+func (c *FaultyCollection) UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	if err := c.injector.inject(); err != nil {
+		return nil, err
+	}
+	return c.CollectionAPI.UpdateMany(ctx, filter, update, opts...)
+}
+
+func (c *FaultyCollection) ReplaceOne(ctx context.Context, filter interface{}, replacement interface{}, opts ...*options.ReplaceOptions) (*mongo.UpdateResult, error) {
+	if err := c.injector.inject(); err != nil {
+		return nil, err
+	}
+	return c.CollectionAPI.ReplaceOne(ctx, filter, replacement, opts...)
+}
+
+func (c *FaultyCollection) DeleteOne(ctx context.Context, filter interface{}) (*mongo.DeleteResult, error) {
+	if err := c.injector.inject(); err != nil {
+		return nil, err
+	}
+	return c.CollectionAPI.DeleteOne(ctx, filter)
+}
+
+func (c *FaultyCollection) DeleteMany(ctx context.Context, filter interface{}) (*mongo.DeleteResult, error) {
+	if err := c.injector.inject(); err != nil {
+		return nil, err
+	}
+	return c.CollectionAPI.DeleteMany(ctx, filter)
+}
+
+func (c *FaultyCollection) FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	if err := c.injector.inject(); err != nil {
+		return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+	}
+	return c.CollectionAPI.FindOneAndUpdate(ctx, filter, update, opts...)
+}
+
+func (c *FaultyCollection) CountDocuments(ctx context.Context, filter interface{}) (int64, error) {
+	if err := c.injector.inject(); err != nil {
+		return 0, err
+	}
+	return c.CollectionAPI.CountDocuments(ctx, filter)
+}
+
+func (c *FaultyCollection) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	if err := c.injector.inject(); err != nil {
+		return nil, err
+	}
+	return c.CollectionAPI.Find(ctx, filter, opts...)
+}
+
+func (c *FaultyCollection) Aggregate(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (*mongo.Cursor, error) {
+	if err := c.injector.inject(); err != nil {
+		return nil, err
+	}
+	return c.CollectionAPI.Aggregate(ctx, pipeline, opts...)
+}
@@ -0,0 +1,181 @@
+package mongobench
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Generator produces a new value for every operation
+type Generator func(r *Randomizer) interface{}
+
+// Template is a document, array or value whose "{{...}}" string placeholders are replaced by generated values.
+// Supported placeholders:
+//
+//	{{int min max}}      random int64 in [min,max]
+//	{{float min max}}    random float64 in [min,max)
+//	{{string n}}         random alphanumeric string of length n
+//	{{pick a b ...}}     one of the given strings
+//	{{objectId}}         new ObjectId
+//	{{now}}              current time
+//	{{date from to}}     random time between now+from and now+to seconds
+type Template struct {
+	generate Generator
+}
+
+// CompileTemplate parses the placeholders of the given BSON value once, so generating values is cheap
+func CompileTemplate(value interface{}) (*Template, error) {
+	generate, err := compileValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{generate: generate}, nil
+}
+
+// Generate returns a copy of the template with every placeholder replaced by a newly generated value
+func (t *Template) Generate(r *Randomizer) interface{} {
+	return t.generate(r)
+}
+
+func compileValue(value interface{}) (Generator, error) {
+	switch v := value.(type) {
+	case bson.D:
+		keys := make([]string, len(v))
+		values := make([]Generator, len(v))
+		for i, element := range v {
+			generate, err := compileValue(element.Value)
+			if err != nil {
+				return nil, err
+			}
+			keys[i], values[i] = element.Key, generate
+		}
+		return func(r *Randomizer) interface{} {
+			doc := make(bson.D, len(keys))
+			for i := range keys {
+				doc[i] = bson.E{Key: keys[i], Value: values[i](r)}
+			}
+			return doc
+		}, nil
+	case bson.A:
+		values := make([]Generator, len(v))
+		for i, element := range v {
+			generate, err := compileValue(element)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = generate
+		}
+		return func(r *Randomizer) interface{} {
+			array := make(bson.A, len(values))
+			for i := range values {
+				array[i] = values[i](r)
+			}
+			return array
+		}, nil
+	case string:
+		if strings.HasPrefix(v, "{{") && strings.HasSuffix(v, "}}") {
+			return parsePlaceholder(strings.Fields(v[2 : len(v)-2]))
+		}
+	}
+	return func(*Randomizer) interface{} { return value }, nil
+}
+
+func parsePlaceholder(fields []string) (Generator, error) {
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty placeholder")
+	}
+	args := fields[1:]
+	switch fields[0] {
+	case "int":
+		low, high, err := parseIntBounds(fields[0], args)
+		if err != nil {
+			return nil, err
+		}
+		// The span is unsigned, as it does not fit an int64 if the range covers more than half of the int64 values
+		span := uint64(high) - uint64(low)
+		return func(r *Randomizer) interface{} {
+			if span == math.MaxUint64 {
+				return int64(r.RandomUint64())
+			}
+			return int64(uint64(low) + r.RandomUint64n(span+1))
+		}, nil
+	case "float":
+		bounds, err := parseBounds(fields[0], args)
+		if err != nil {
+			return nil, err
+		}
+		return func(r *Randomizer) interface{} { return bounds[0] + r.RandomFloat64()*(bounds[1]-bounds[0]) }, nil
+	case "string":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("placeholder string expects a length")
+		}
+		length, err := strconv.Atoi(args[0])
+		if err != nil || length < 0 {
+			return nil, fmt.Errorf("invalid length for placeholder string: %s", args[0])
+		}
+		return func(r *Randomizer) interface{} { return r.RandomString(length) }, nil
+	case "pick":
+		if len(args) == 0 {
+			return nil, fmt.Errorf("placeholder pick expects at least one value")
+		}
+		return func(r *Randomizer) interface{} { return args[r.RandomIntn(len(args))] }, nil
+	case "objectId":
+		return func(*Randomizer) interface{} { return primitive.NewObjectID() }, nil
+	case "now":
+		return func(*Randomizer) interface{} { return primitive.NewDateTimeFromTime(time.Now()) }, nil
+	case "date":
+		bounds, err := parseBounds(fields[0], args)
+		if err != nil {
+			return nil, err
+		}
+		from, to := time.Duration(bounds[0]*float64(time.Second)), time.Duration(bounds[1]*float64(time.Second))
+		return func(r *Randomizer) interface{} {
+			offset := from + time.Duration(r.RandomInt63n(int64(to-from)+1))
+			return primitive.NewDateTimeFromTime(time.Now().Add(offset))
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown placeholder %q", fields[0])
+	}
+}
+
+// parseIntBounds parses the bounds of an integer placeholder exactly, as integers above 2^53 are no exact float64
+func parseIntBounds(name string, args []string) (int64, int64, error) {
+	if len(args) != 2 {
+		return 0, 0, fmt.Errorf("placeholder %s expects a minimum and a maximum", name)
+	}
+	var bounds [2]int64
+	for i, arg := range args {
+		value, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid bound for placeholder %s: %s", name, arg)
+		}
+		bounds[i] = value
+	}
+	if bounds[0] > bounds[1] {
+		return 0, 0, fmt.Errorf("placeholder %s has a minimum greater than its maximum", name)
+	}
+	return bounds[0], bounds[1], nil
+}
+
+func parseBounds(name string, args []string) ([2]float64, error) {
+	var bounds [2]float64
+	if len(args) != 2 {
+		return bounds, fmt.Errorf("placeholder %s expects a minimum and a maximum", name)
+	}
+	for i, arg := range args {
+		value, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return bounds, fmt.Errorf("invalid bound for placeholder %s: %s", name, arg)
+		}
+		bounds[i] = value
+	}
+	if bounds[0] > bounds[1] {
+		return bounds, fmt.Errorf("placeholder %s has a minimum greater than its maximum", name)
+	}
+	return bounds, nil
+}
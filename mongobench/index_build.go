@@ -0,0 +1,162 @@
+package mongobench
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var indexBuildPhases = []string{"before", "during", "after"}
+
+// indexBuild triggers an index build while the test workload runs and attributes every operation to the phase
+// before, during or after the build, so the dip in throughput and latency the build causes becomes visible
+type indexBuild struct {
+	indexes []mongo.IndexModel
+	delay   time.Duration
+
+	phase      atomic.Int32
+	counts     [3]atomic.Int64
+	latencies  [3]metrics.Histogram
+	boundaries [4]time.Time
+	err        error
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newIndexBuild(config TestingConfig) *indexBuild {
+	if len(config.IndexBuild) == 0 {
+		return nil
+	}
+	b := &indexBuild{
+		indexes: config.IndexBuild,
+		delay:   config.IndexBuildDelay,
+		stop:    make(chan struct{}),
+	}
+	for i := range b.latencies {
+		b.latencies[i] = metrics.NewHistogram(metrics.NewUniformSample(100000))
+	}
+	return b
+}
+
+// start schedules the index build after the configured delay; call it when the workload starts
+func (b *indexBuild) start(collection CollectionAPI) {
+	b.boundaries[0] = time.Now()
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		select {
+		case <-b.stop:
+			return
+		case <-time.After(b.delay):
+		}
+
+		logger.Printf("Starting index build...")
+		b.boundaries[1] = time.Now()
+		b.phase.Store(1)
+		_, b.err = collection.CreateIndexes(context.Background(), b.indexes)
+		b.boundaries[2] = time.Now()
+		b.phase.Store(2)
+
+		if b.err != nil {
+			logger.Printf("Index build failed after %v: %v", b.boundaries[2].Sub(b.boundaries[1]), b.err)
+		} else {
+			logger.Printf("Index build completed in %v", b.boundaries[2].Sub(b.boundaries[1]))
+		}
+	}()
+}
+
+// observe attributes a successful operation to the current phase
+func (b *indexBuild) observe(elapsed time.Duration) {
+	phase := b.phase.Load()
+	b.counts[phase].Add(1)
+	b.latencies[phase].Update(int64(elapsed))
+}
+
+// stopped ends the measured phases when the workers stop, even if the build is still running; a build that has not
+// started yet is skipped
+func (b *indexBuild) stopped() {
+	b.boundaries[3] = time.Now()
+	close(b.stop)
+}
+
+// finish waits for a running index build to complete and returns the error it failed with
+func (b *indexBuild) finish() error {
+	b.wg.Wait()
+	return b.err
+}
+
+// Columns implements MetricsSource
+func (b *indexBuild) Columns() []string {
+	return []string{"index_build"}
+}
+
+// Values implements MetricsSource
+func (b *indexBuild) Values() []string {
+	return []string{indexBuildPhases[b.phase.Load()]}
+}
+
+// writeReport saves the duration of the build and the throughput and latency of the workload in each phase. The
+// phases end with the workload, so a build outlasting it reports only the part the workload ran alongside.
+func (b *indexBuild) writeReport(output Output, filename string) error {
+	records := [][]string{{"phase", "start", "duration_s", "count", "mean_rate", "latency_mean_ms", "latency_p99_ms", "relative_rate", "status"}}
+	var baseline float64
+
+	stopped := b.boundaries[3]
+	for phase, name := range indexBuildPhases {
+		start, end := b.boundaries[phase], b.boundaries[phase+1]
+		if end.IsZero() || end.After(stopped) {
+			end = stopped
+		}
+		if start.IsZero() || !start.Before(end) {
+			continue
+		}
+		status := ""
+		if phase == 1 {
+			switch {
+			case b.err != nil:
+				status = fmt.Sprintf("failed: %v", b.err)
+			case b.boundaries[2].After(stopped):
+				status = "outlasted the workload"
+			default:
+				status = "completed"
+			}
+		}
+
+		duration := end.Sub(start).Seconds()
+		count := b.counts[phase].Load()
+		rate := 0.0
+		if duration > 0 {
+			rate = float64(count) / duration
+		}
+		if phase == 0 {
+			baseline = rate
+		}
+		relative := 0.0
+		if baseline > 0 {
+			relative = rate / baseline
+		}
+		latency := b.latencies[phase].Snapshot()
+		records = append(records, []string{
+			name,
+			fmt.Sprintf("%d", start.Unix()),
+			fmt.Sprintf("%.3f", duration),
+			fmt.Sprintf("%d", count),
+			fmt.Sprintf("%.6f", rate),
+			formatMillis(latency.Mean()),
+			formatMillis(latency.Percentile(0.99)),
+			fmt.Sprintf("%.4f", relative),
+			status,
+		})
+	}
+
+	if b.boundaries[1].IsZero() {
+		logger.Printf("The test ended before the index build was due to start")
+	}
+	return output.save("Index build report", filename, records)
+}
@@ -0,0 +1,157 @@
+package mongobench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// indexSpec is the declaration of a secondary index in the index file, following the createIndexes syntax
+type indexSpec struct {
+	Key                     bson.D `bson:"key"`
+	Name                    string `bson:"name"`
+	Unique                  bool   `bson:"unique"`
+	Sparse                  bool   `bson:"sparse"`
+	Hidden                  bool   `bson:"hidden"`
+	ExpireAfterSeconds      *int32 `bson:"expireAfterSeconds"`
+	PartialFilterExpression bson.D `bson:"partialFilterExpression"`
+	WildcardProjection      bson.D `bson:"wildcardProjection"`
+	Weights                 bson.D `bson:"weights"`
+	DefaultLanguage         string `bson:"default_language"`
+}
+
+// LoadIndexes reads the indexes declared in an Extended JSON array of index specifications. Every index is named,
+// so results can be attributed to it.
+func LoadIndexes(path string) ([]mongo.IndexModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index file: %v", err)
+	}
+	var specs []indexSpec
+	if err := bson.UnmarshalExtJSON(data, false, &specs); err != nil {
+		return nil, fmt.Errorf("failed to parse index file %s: %v", path, err)
+	}
+
+	var models []mongo.IndexModel
+	for i, spec := range specs {
+		if len(spec.Key) == 0 {
+			return nil, fmt.Errorf("index %d in %s has no key", i, path)
+		}
+		name := spec.Name
+		if name == "" {
+			name = indexName(spec.Key)
+		}
+
+		opts := options.Index().SetName(name)
+		if spec.Unique {
+			opts.SetUnique(true)
+		}
+		if spec.Sparse {
+			opts.SetSparse(true)
+		}
+		if spec.Hidden {
+			opts.SetHidden(true)
+		}
+		if spec.ExpireAfterSeconds != nil {
+			opts.SetExpireAfterSeconds(*spec.ExpireAfterSeconds)
+		}
+		if spec.PartialFilterExpression != nil {
+			opts.SetPartialFilterExpression(spec.PartialFilterExpression)
+		}
+		if spec.WildcardProjection != nil {
+			opts.SetWildcardProjection(spec.WildcardProjection)
+		}
+		if spec.Weights != nil {
+			opts.SetWeights(spec.Weights)
+		}
+		if spec.DefaultLanguage != "" {
+			opts.SetDefaultLanguage(spec.DefaultLanguage)
+		}
+		models = append(models, mongo.IndexModel{Keys: spec.Key, Options: opts})
+	}
+	return models, nil
+}
+
+// indexName derives the name the server would give an index with the given key, e.g. rnd_1_v_-1
+func indexName(key bson.D) string {
+	var parts []string
+	for _, element := range key {
+		parts = append(parts, fmt.Sprintf("%s_%v", element.Key, element.Value))
+	}
+	return strings.Join(parts, "_")
+}
+
+// createIndexes creates the declared indexes of the configuration; existing identical indexes are left untouched
+func createIndexes(collection CollectionAPI, config TestingConfig) error {
+	if len(config.Indexes) == 0 {
+		return nil
+	}
+	names, err := collection.CreateIndexes(context.Background(), config.Indexes)
+	if err != nil {
+		return fmt.Errorf("failed to create indexes: %v", err)
+	}
+	logger.Printf("Created indexes: %s", strings.Join(names, ", "))
+	return nil
+}
+
+// RunIndexImpact runs the same test with 0..N of the declared indexes and saves the throughput and latency of each
+// run, showing the write amplification every additional index causes. It returns the result of every run.
+func (t Runner) RunIndexImpact(collection CollectionAPI, testType string, config TestingConfig) ([]TestResult, error) {
+	indexes := config.Indexes
+	records := [][]string{{"indexes", "added_index", "count", "mean_rate", "latency_mean_ms", "latency_p99_ms", "relative_rate"}}
+	var results []TestResult
+	var baseline float64
+
+	for n := 0; n <= len(indexes); n++ {
+		if err := collection.DropIndexes(context.Background()); err != nil && !isNamespaceNotFound(err) {
+			return results, fmt.Errorf("failed to drop indexes: %v", err)
+		}
+
+		runConfig := config
+		runConfig.Indexes = indexes[:n]
+		runConfig.RunLabel = fmt.Sprintf("%didx", n)
+		addedIndex := "_id"
+		if n > 0 {
+			addedIndex = *indexes[n-1].Options.Name
+		}
+		logger.Printf("Running %s test with %d secondary indexes", testType, n)
+
+		result, err := t.Run(collection, testType, runConfig)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+		if n == 0 {
+			baseline = result.MeanRate
+		}
+		relative := 0.0
+		if baseline > 0 {
+			relative = result.MeanRate / baseline
+		}
+		records = append(records, []string{
+			fmt.Sprintf("%d", n),
+			addedIndex,
+			fmt.Sprintf("%d", result.Count),
+			fmt.Sprintf("%.6f", result.MeanRate),
+			formatMillis(float64(result.LatencyMean)),
+			formatMillis(float64(result.LatencyP99)),
+			fmt.Sprintf("%.4f", relative),
+		})
+	}
+
+	if err := config.Output.save("Index impact", fmt.Sprintf("index_impact_%s.csv", testType), records); err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+func isNamespaceNotFound(err error) bool {
+	var commandErr mongo.CommandError
+	return errors.As(err, &commandErr) && commandErr.Code == 26
+}
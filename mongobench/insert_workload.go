@@ -0,0 +1,40 @@
+package mongobench
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func init() {
+	RegisterWorkload(WorkloadDefinition{Name: "insert", Fresh: true, New: newInsertWorkload})
+}
+
+// insertWorkload inserts newly generated documents
+type insertWorkload struct {
+	BaseWorkload
+	env WorkloadEnv
+}
+
+func newInsertWorkload(env WorkloadEnv) (Workload, error) {
+	return &insertWorkload{env: env}, nil
+}
+
+func (w *insertWorkload) Run(collection CollectionAPI, worker *Worker) error {
+	doc := newDocument(worker.ID, worker.Random, w.env.Config, w.env.Data)
+	if w.env.Writes == nil {
+		return w.env.Config.Retry.run(w.env.RetryStats, func() error {
+			_, err := collection.InsertOne(context.Background(), doc)
+			return err
+		})
+	}
+	// The ID is generated up front, so the verification knows which document an acknowledged insert wrote
+	id := primitive.NewObjectID()
+	doc["_id"] = id
+	err := w.env.Config.Retry.run(w.env.RetryStats, func() error {
+		_, err := collection.InsertOne(context.Background(), doc)
+		return err
+	})
+	w.env.Writes.Wrote(id, doc, err)
+	return err
+}
@@ -0,0 +1,511 @@
+package mongobench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrInjected is returned by the operations of a MemoryCollection that were chosen to fail
+var ErrInjected = errors.New("injected failure")
+
+// MemoryCollection is a CollectionAPI that keeps the documents in memory, for dry runs of scenarios and tests without
+// a server. It supports inserts, updates with $set, upserts, replacements, deletes, counts, and finds and aggregations
+// with $match and $sample. Filters match fields by equality. Every data operation is delayed by Latency and fails
+// with ErrInjected at the FailureRate.
+type MemoryCollection struct {
+	Latency     time.Duration
+	FailureRate float64 // share of operations that fail, between 0 and 1
+
+	mu     sync.Mutex
+	docs   map[interface{}]bson.M
+	random *Randomizer
+}
+
+func NewMemoryCollection(latency time.Duration, failureRate float64) *MemoryCollection {
+	return &MemoryCollection{
+		Latency:     latency,
+		FailureRate: failureRate,
+		docs:        make(map[interface{}]bson.M),
+		random:      NewRandomizer(),
+	}
+}
+
+// inject delays the operation by the latency and returns ErrInjected if the operation is chosen to fail
+func (c *MemoryCollection) inject() error {
+	if c.Latency > 0 {
+		time.Sleep(c.Latency)
+	}
+	if c.FailureRate > 0 {
+		c.mu.Lock()
+		fail := c.random.RandomFloat64() < c.FailureRate
+		c.mu.Unlock()
+		if fail {
+			return ErrInjected
+		}
+	}
+	return nil
+}
+
+func (c *MemoryCollection) InsertOne(_ context.Context, document interface{}) (*mongo.InsertOneResult, error) {
+	if err := c.inject(); err != nil {
+		return nil, err
+	}
+	doc, err := toDocument(document)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id, err := c.insert(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &mongo.InsertOneResult{InsertedID: id}, nil
+}
+
+func (c *MemoryCollection) InsertMany(_ context.Context, documents []interface{}) (*mongo.InsertManyResult, error) {
+	if err := c.inject(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := &mongo.InsertManyResult{}
+	for _, document := range documents {
+		doc, err := toDocument(document)
+		if err != nil {
+			return result, err
+		}
+		id, err := c.insert(doc)
+		if err != nil {
+			return result, err
+		}
+		result.InsertedIDs = append(result.InsertedIDs, id)
+	}
+	return result, nil
+}
+
+// insert stores the document, generating its _id if it has none; the caller holds the lock
+func (c *MemoryCollection) insert(doc bson.M) (interface{}, error) {
+	if _, ok := doc["_id"]; !ok {
+		doc["_id"] = primitive.NewObjectID()
+	}
+	id := doc["_id"]
+	if _, exists := c.docs[id]; exists {
+		return nil, mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000, Message: fmt.Sprintf("E11000 duplicate key error, _id: %v", id)}}}
+	}
+	c.docs[id] = doc
+	return id, nil
+}
+
+func (c *MemoryCollection) UpdateOne(_ context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return c.update(filter, update, false, options.MergeUpdateOptions(opts...))
+}
+
+func (c *MemoryCollection) UpdateMany(_ context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return c.update(filter, update, true, options.MergeUpdateOptions(opts...))
+}
+
+func (c *MemoryCollection) update(filter interface{}, update interface{}, many bool, opts *options.UpdateOptions) (*mongo.UpdateResult, error) {
+	if err := c.inject(); err != nil {
+		return nil, err
+	}
+	match, err := toFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	set, err := setFields(update)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := &mongo.UpdateResult{}
+	for _, doc := range c.matching(match, many) {
+		result.MatchedCount++
+		if applySet(doc, set) {
+			result.ModifiedCount++
+		}
+	}
+	if result.MatchedCount == 0 && opts.Upsert != nil && *opts.Upsert {
+		doc := bson.M{}
+		for key, value := range match {
+			if !strings.HasPrefix(key, "$") {
+				doc[key] = value
+			}
+		}
+		applySet(doc, set)
+		if result.UpsertedID, err = c.insert(doc); err != nil {
+			return nil, err
+		}
+		result.UpsertedCount = 1
+	}
+	return result, nil
+}
+
+func (c *MemoryCollection) ReplaceOne(_ context.Context, filter interface{}, replacement interface{}, opts ...*options.ReplaceOptions) (*mongo.UpdateResult, error) {
+	if err := c.inject(); err != nil {
+		return nil, err
+	}
+	match, err := toFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := toDocument(replacement)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := &mongo.UpdateResult{}
+	for _, existing := range c.matching(match, false) {
+		doc["_id"] = existing["_id"]
+		c.docs[existing["_id"]] = doc
+		result.MatchedCount, result.ModifiedCount = 1, 1
+	}
+	if upsert := options.MergeReplaceOptions(opts...).Upsert; result.MatchedCount == 0 && upsert != nil && *upsert {
+		if id, ok := match["_id"]; ok {
+			doc["_id"] = id
+		}
+		if result.UpsertedID, err = c.insert(doc); err != nil {
+			return nil, err
+		}
+		result.UpsertedCount = 1
+	}
+	return result, nil
+}
+
+func (c *MemoryCollection) DeleteOne(_ context.Context, filter interface{}) (*mongo.DeleteResult, error) {
+	return c.delete(filter, false)
+}
+
+func (c *MemoryCollection) DeleteMany(_ context.Context, filter interface{}) (*mongo.DeleteResult, error) {
+	return c.delete(filter, true)
+}
+
+func (c *MemoryCollection) delete(filter interface{}, many bool) (*mongo.DeleteResult, error) {
+	if err := c.inject(); err != nil {
+		return nil, err
+	}
+	match, err := toFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := &mongo.DeleteResult{}
+	for _, doc := range c.matching(match, many) {
+		delete(c.docs, doc["_id"])
+		result.DeletedCount++
+	}
+	return result, nil
+}
+
+func (c *MemoryCollection) FindOneAndUpdate(_ context.Context, _ interface{}, _ interface{}, _ ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	return mongo.NewSingleResultFromDocument(bson.D{}, errMemoryUnsupported("findOneAndUpdate"), nil)
+}
+
+func (c *MemoryCollection) CountDocuments(_ context.Context, filter interface{}) (int64, error) {
+	if err := c.inject(); err != nil {
+		return 0, err
+	}
+	match, err := toFilter(filter)
+	if err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return int64(len(c.matching(match, true))), nil
+}
+
+func (c *MemoryCollection) Find(_ context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	if err := c.inject(); err != nil {
+		return nil, err
+	}
+	match, err := toFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	docs := c.matching(match, true)
+	if limit := options.MergeFindOptions(opts...).Limit; limit != nil && *limit > 0 && int64(len(docs)) > *limit {
+		docs = docs[:*limit]
+	}
+	return cursorOf(docs)
+}
+
+// Aggregate supports pipelines of $match, $sample and $limit stages
+func (c *MemoryCollection) Aggregate(_ context.Context, pipeline interface{}, _ ...*options.AggregateOptions) (*mongo.Cursor, error) {
+	if err := c.inject(); err != nil {
+		return nil, err
+	}
+	stages, err := toPipeline(pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	docs := c.matching(bson.M{}, true)
+	for _, stage := range stages {
+		for name, spec := range stage {
+			switch name {
+			case "$match":
+				match, _ := spec.(bson.M)
+				var matched []bson.M
+				for _, doc := range docs {
+					if matches(doc, match) {
+						matched = append(matched, doc)
+					}
+				}
+				docs = matched
+			case "$sample", "$limit":
+				size, ok := toFloat(spec)
+				if name == "$sample" {
+					sample, _ := spec.(bson.M)
+					size, ok = toFloat(sample["size"])
+					for i := range docs {
+						j := i + c.random.RandomIntn(len(docs)-i)
+						docs[i], docs[j] = docs[j], docs[i]
+					}
+				}
+				if !ok {
+					return nil, fmt.Errorf("invalid %s stage: %v", name, spec)
+				}
+				if int(size) < len(docs) {
+					docs = docs[:int(size)]
+				}
+			default:
+				return nil, errMemoryUnsupported(name + " stage")
+			}
+		}
+	}
+	return cursorOf(docs)
+}
+
+func (c *MemoryCollection) Drop(_ context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.docs = make(map[interface{}]bson.M)
+	return nil
+}
+
+// CreateIndexes only returns the names of the indexes, lookups by _id are the only ones that are fast
+func (c *MemoryCollection) CreateIndexes(_ context.Context, models []mongo.IndexModel) ([]string, error) {
+	var names []string
+	for _, model := range models {
+		if model.Options != nil && model.Options.Name != nil {
+			names = append(names, *model.Options.Name)
+			continue
+		}
+		keys, err := toDocument(model.Keys)
+		if err != nil {
+			return nil, err
+		}
+		var parts []string
+		for key, value := range keys {
+			parts = append(parts, fmt.Sprintf("%s_%v", key, value))
+		}
+		sort.Strings(parts)
+		names = append(names, strings.Join(parts, "_"))
+	}
+	return names, nil
+}
+
+func (c *MemoryCollection) DropIndexes(_ context.Context) error {
+	return nil
+}
+
+func (c *MemoryCollection) CreateCollection(_ context.Context, _ ...*options.CreateCollectionOptions) error {
+	return nil
+}
+
+func (c *MemoryCollection) Watch(_ context.Context, _ interface{}, _ ...*options.ChangeStreamOptions) (*mongo.ChangeStream, error) {
+	return nil, errMemoryUnsupported("the change stream")
+}
+
+// matching returns the documents matching the filter, or the first one unless many is set; the caller holds the lock
+func (c *MemoryCollection) matching(filter bson.M, many bool) []bson.M {
+	if id, ok := filter["_id"]; ok && len(filter) == 1 {
+		if doc, found := c.docs[id]; found {
+			return []bson.M{doc}
+		}
+		return nil
+	}
+	var docs []bson.M
+	for _, doc := range c.docs {
+		if matches(doc, filter) {
+			docs = append(docs, doc)
+			if !many {
+				break
+			}
+		}
+	}
+	return docs
+}
+
+// matches reports whether every field of the filter equals the field of the document
+func matches(doc bson.M, filter bson.M) bool {
+	for key, value := range filter {
+		if !valuesEqual(doc[key], value) {
+			return false
+		}
+	}
+	return true
+}
+
+// valuesEqual compares numbers by value regardless of their BSON type, and everything else deeply
+func valuesEqual(a, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// setFields returns the fields of the $set operator of an update, the only operator the collection supports
+func setFields(update interface{}) (bson.M, error) {
+	if _, pipeline := update.(bson.A); pipeline {
+		return nil, errMemoryUnsupported("the pipeline update")
+	}
+	doc, err := toDocument(update)
+	if err != nil {
+		return nil, err
+	}
+	for operator := range doc {
+		if operator != "$set" {
+			return nil, errMemoryUnsupported(operator + " operator")
+		}
+	}
+	set, _ := doc["$set"].(bson.M)
+	return set, nil
+}
+
+// applySet sets the fields on the document and reports whether any of them changed
+func applySet(doc bson.M, set bson.M) bool {
+	changed := false
+	for key, value := range set {
+		if !reflect.DeepEqual(doc[key], value) {
+			doc[key] = value
+			changed = true
+		}
+	}
+	return changed
+}
+
+// toPipeline converts a pipeline into its stages, rejecting the stages and filters the collection cannot evaluate
+func toPipeline(pipeline interface{}) ([]bson.M, error) {
+	var wrapper struct {
+		Stages []bson.M `bson:"pipeline"`
+	}
+	data, err := bson.Marshal(bson.M{"pipeline": pipeline})
+	if err == nil {
+		err = bson.Unmarshal(data, &wrapper)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid pipeline: %v", err)
+	}
+	for _, stage := range wrapper.Stages {
+		for name, spec := range stage {
+			switch name {
+			case "$match":
+				match, _ := spec.(bson.M)
+				if err := checkFilter(match); err != nil {
+					return nil, err
+				}
+			case "$sample", "$limit":
+			default:
+				return nil, errMemoryUnsupported(name + " stage")
+			}
+		}
+	}
+	return wrapper.Stages, nil
+}
+
+// toFilter converts a filter like toDocument, rejecting the query operators the collection cannot evaluate
+func toFilter(filter interface{}) (bson.M, error) {
+	match, err := toDocument(filter)
+	if err != nil {
+		return nil, err
+	}
+	return match, checkFilter(match)
+}
+
+// checkFilter returns an error if the filter uses an operator, as only equality on fields is supported
+func checkFilter(filter bson.M) error {
+	for key, value := range filter {
+		if strings.HasPrefix(key, "$") {
+			return errMemoryUnsupported(key + " operator")
+		}
+		switch nested := value.(type) {
+		case bson.M:
+			for operator := range nested {
+				if strings.HasPrefix(operator, "$") {
+					return errMemoryUnsupported(operator + " operator")
+				}
+			}
+		case bson.D:
+			for _, element := range nested {
+				if strings.HasPrefix(element.Key, "$") {
+					return errMemoryUnsupported(element.Key + " operator")
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// toDocument converts a document of any type the driver accepts into a bson.M with the types BSON decodes into
+func toDocument(document interface{}) (bson.M, error) {
+	if document == nil {
+		return bson.M{}, nil
+	}
+	data, err := bson.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document: %v", err)
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid document: %v", err)
+	}
+	return doc, nil
+}
+
+func cursorOf(docs []bson.M) (*mongo.Cursor, error) {
+	documents := make([]interface{}, len(docs))
+	for i, doc := range docs {
+		documents[i] = doc
+	}
+	return mongo.NewCursorFromDocuments(documents, nil, nil)
+}
+
+func errMemoryUnsupported(feature string) error {
+	return fmt.Errorf("%s is not supported by the in-memory collection", feature)
+}
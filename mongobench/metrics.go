@@ -0,0 +1,221 @@
+package mongobench
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MetricsSource contributes additional columns to the per-second benchmark output
+type MetricsSource interface {
+	Columns() []string
+	Values() []string
+}
+
+// metricsRecorder samples the operation meter every second, logs the rates and keeps the records in memory
+// until the test completes, so no disk I/O happens during the benchmark run.
+type metricsRecorder struct {
+	rate    metrics.Meter
+	latency metrics.Histogram
+	overall metrics.Histogram
+	started time.Time
+	// observer, if set, is additionally told about the latency of every successful operation
+	observer interface{ observe(elapsed time.Duration) }
+	// intervalObserver, if set, is told about the p99 latency of every one-second interval
+	intervalObserver interface{ observeInterval(p99 time.Duration) }
+	sources          []MetricsSource
+	ticker           *time.Ticker
+	done             chan struct{}
+	mu               sync.Mutex
+	records          [][]string
+}
+
+func newMetricsRecorder(header []string, sources ...MetricsSource) *metricsRecorder {
+	row := append(append([]string{}, header...), "latency_mean_ms", "latency_p99_ms")
+	for _, source := range sources {
+		row = append(row, source.Columns()...)
+	}
+	return &metricsRecorder{
+		rate:    metrics.NewMeter(),
+		latency: metrics.NewHistogram(metrics.NewUniformSample(4096)),
+		overall: metrics.NewHistogram(metrics.NewUniformSample(100000)),
+		sources: sources,
+		done:    make(chan struct{}),
+		records: [][]string{row},
+	}
+}
+
+// mark records a successful operation that was started at the given time
+func (m *metricsRecorder) mark(start time.Time) {
+	elapsed := time.Since(start)
+	m.rate.Mark(1)
+	m.latency.Update(int64(elapsed))
+	m.overall.Update(int64(elapsed))
+	if m.observer != nil {
+		m.observer.observe(elapsed)
+	}
+}
+
+// start begins the per-second sampling; call it just before launching the workload goroutines
+func (m *metricsRecorder) start() {
+	m.started = time.Now()
+	m.ticker = time.NewTicker(1 * time.Second)
+	go func() {
+		for {
+			select {
+			case <-m.done:
+				return
+			case <-m.ticker.C:
+				m.record(true)
+			}
+		}
+	}()
+}
+
+// stop ends the sampling and records the final metrics
+func (m *metricsRecorder) stop() {
+	if m.ticker != nil {
+		m.ticker.Stop()
+		close(m.done)
+	}
+	m.record(false)
+}
+
+func (m *metricsRecorder) record(logLine bool) {
+	timestamp := time.Now().Unix()
+	count := m.rate.Count()
+	mean := m.rate.RateMean()
+	m1Rate := m.rate.Rate1()
+	m5Rate := m.rate.Rate5()
+	m15Rate := m.rate.Rate15()
+	latency := m.latency.Snapshot()
+	m.latency.Clear()
+	if logLine && m.intervalObserver != nil && latency.Count() > 0 {
+		m.intervalObserver.observeInterval(time.Duration(latency.Percentile(0.99)))
+	}
+
+	record := []string{
+		fmt.Sprintf("%d", timestamp),
+		fmt.Sprintf("%d", count),
+		fmt.Sprintf("%.6f", mean),
+		fmt.Sprintf("%.6f", m1Rate),
+		fmt.Sprintf("%.6f", m5Rate),
+		fmt.Sprintf("%.6f", m15Rate),
+		formatMillis(latency.Mean()),
+		formatMillis(latency.Percentile(0.99)),
+	}
+	var extra []string
+	for _, source := range m.sources {
+		columns, values := source.Columns(), source.Values()
+		record = append(record, values...)
+		for i := range columns {
+			extra = append(extra, fmt.Sprintf("%s: %s", columns[i], values[i]))
+		}
+	}
+
+	if logLine {
+		line := fmt.Sprintf("Timestamp: %d, Document Count: %d, Mean Rate: %.2f docs/sec, m1_rate: %.2f, m5_rate: %.2f, m15_rate: %.2f, latency_mean_ms: %s, latency_p99_ms: %s",
+			timestamp, count, mean, m1Rate, m5Rate, m15Rate, record[6], record[7])
+		if len(extra) > 0 {
+			line += ", " + strings.Join(extra, ", ")
+		}
+		logger.Println(line)
+	}
+
+	m.mu.Lock()
+	m.records = append(m.records, record)
+	m.mu.Unlock()
+}
+
+// result summarizes the whole test; call it after stop
+func (m *metricsRecorder) result(testType string) TestResult {
+	overall := m.overall.Snapshot()
+	return TestResult{
+		TestType:    testType,
+		Count:       m.rate.Count(),
+		Duration:    time.Since(m.started),
+		MeanRate:    m.rate.RateMean(),
+		LatencyMean: time.Duration(overall.Mean()),
+		LatencyP99:  time.Duration(overall.Percentile(0.99)),
+	}
+}
+
+// writeCSV saves the recorded metrics to the given file of the output
+func (m *metricsRecorder) writeCSV(output Output, filename string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return output.save("Results", filename, m.records)
+}
+
+// QueryStats counts the documents and bytes returned by read workloads and the time until their first document
+// arrived. The documents per second and time to first document are reported per interval between two samples.
+type QueryStats struct {
+	docs          atomic.Int64
+	bytes         atomic.Int64
+	firstDocument metrics.Histogram
+
+	mu           sync.Mutex
+	previousDocs int64
+	previousTime time.Time
+}
+
+func NewQueryStats() *QueryStats {
+	return &QueryStats{
+		firstDocument: metrics.NewHistogram(metrics.NewUniformSample(4096)),
+		previousTime:  time.Now(),
+	}
+}
+
+// consume fully iterates and closes the cursor of a query that was issued at the given time
+func (s *QueryStats) consume(cursor *mongo.Cursor, start time.Time) error {
+	defer cursor.Close(context.Background())
+
+	var docs, bytes int64
+	for cursor.Next(context.Background()) {
+		if docs == 0 {
+			s.firstDocument.Update(int64(time.Since(start)))
+		}
+		docs++
+		bytes += int64(len(cursor.Current))
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	s.docs.Add(docs)
+	s.bytes.Add(bytes)
+	return nil
+}
+
+// Columns implements MetricsSource
+func (s *QueryStats) Columns() []string {
+	return []string{"docs_returned", "bytes_returned", "docs_per_sec", "first_doc_mean_ms", "first_doc_p99_ms"}
+}
+
+// Values implements MetricsSource
+func (s *QueryStats) Values() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	docs := s.docs.Load()
+	docsPerSec := float64(docs-s.previousDocs) / now.Sub(s.previousTime).Seconds()
+	s.previousDocs, s.previousTime = docs, now
+
+	firstDocument := s.firstDocument.Snapshot()
+	s.firstDocument.Clear()
+
+	return []string{
+		fmt.Sprintf("%d", docs),
+		fmt.Sprintf("%d", s.bytes.Load()),
+		fmt.Sprintf("%.2f", docsPerSec),
+		formatMillis(firstDocument.Mean()),
+		formatMillis(firstDocument.Percentile(0.99)),
+	}
+}
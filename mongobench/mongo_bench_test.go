@@ -0,0 +1,1451 @@
+package mongobench
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/address"
+	"go.mongodb.org/mongo-driver/mongo/description"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MockCollection to mock MongoDB collection operations
+type MockCollection struct {
+	mock.Mock
+}
+
+func (m *MockCollection) InsertOne(ctx context.Context, document interface{}) (*mongo.InsertOneResult, error) {
+	args := m.Called(ctx, document)
+	return args.Get(0).(*mongo.InsertOneResult), args.Error(1)
+}
+
+func (m *MockCollection) InsertMany(ctx context.Context, documents []interface{}) (*mongo.InsertManyResult, error) {
+	args := m.Called(ctx, documents)
+	return args.Get(0).(*mongo.InsertManyResult), args.Error(1)
+}
+
+func (m *MockCollection) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	args := m.Called(ctx, filter, update, opts)
+	return args.Get(0).(*mongo.UpdateResult), args.Error(1)
+}
+
+func (m *MockCollection) UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	args := m.Called(ctx, filter, update, opts)
+	return args.Get(0).(*mongo.UpdateResult), args.Error(1)
+}
+
+func (m *MockCollection) ReplaceOne(ctx context.Context, filter interface{}, replacement interface{}, opts ...*options.ReplaceOptions) (*mongo.UpdateResult, error) {
+	args := m.Called(ctx, filter, replacement, opts)
+	return args.Get(0).(*mongo.UpdateResult), args.Error(1)
+}
+
+func (m *MockCollection) DeleteMany(ctx context.Context, filter interface{}) (*mongo.DeleteResult, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(*mongo.DeleteResult), args.Error(1)
+}
+
+func (m *MockCollection) DeleteOne(ctx context.Context, filter interface{}) (*mongo.DeleteResult, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(*mongo.DeleteResult), args.Error(1)
+}
+
+func (m *MockCollection) FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	args := m.Called(ctx, filter, update, opts)
+	return args.Get(0).(*mongo.SingleResult)
+}
+
+func (m *MockCollection) CountDocuments(ctx context.Context, filter interface{}) (int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockCollection) Drop(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *MockCollection) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	args := m.Called(ctx, filter, opts)
+	return args.Get(0).(*mongo.Cursor), args.Error(1)
+}
+
+func (m *MockCollection) Aggregate(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (*mongo.Cursor, error) {
+	args := m.Called(ctx, pipeline, opts)
+	return args.Get(0).(*mongo.Cursor), args.Error(1)
+}
+
+func (m *MockCollection) CreateIndexes(ctx context.Context, models []mongo.IndexModel) ([]string, error) {
+	args := m.Called(ctx, models)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockCollection) DropIndexes(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *MockCollection) CreateCollection(ctx context.Context, opts ...*options.CreateCollectionOptions) error {
+	args := m.Called(ctx, opts)
+	return args.Error(0)
+}
+
+func (m *MockCollection) Watch(ctx context.Context, pipeline interface{}, opts ...*options.ChangeStreamOptions) (*mongo.ChangeStream, error) {
+	args := m.Called(ctx, pipeline, opts)
+	stream, _ := args.Get(0).(*mongo.ChangeStream)
+	return stream, args.Error(1)
+}
+
+// fetchDocumentIDsMock returns a slice of mock ObjectIDs for testing
+func fetchDocumentIDsMock(_ CollectionAPI, _ int64, _ string) ([]primitive.ObjectID, error) {
+	return []primitive.ObjectID{
+		primitive.NewObjectID(),
+		primitive.NewObjectID(),
+		primitive.NewObjectID(),
+		primitive.NewObjectID(),
+		primitive.NewObjectID(),
+		primitive.NewObjectID(),
+		primitive.NewObjectID(),
+		primitive.NewObjectID(),
+		primitive.NewObjectID(),
+		primitive.NewObjectID(),
+	}, nil
+}
+
+// runTest runs a test with the mocked document IDs; like the command line, it stops after config.DocCount operations
+// unless the config sets a stop condition
+func runTest(t *testing.T, collection CollectionAPI, testType string, config TestingConfig) TestResult {
+	t.Helper()
+	if !config.Stop.enabled() {
+		config.Stop.MaxOps = int64(config.DocCount)
+	}
+	result, err := Runner{}.run(collection, testType, config, fetchDocumentIDsMock)
+	assert.NoError(t, err)
+	return result
+}
+
+// TestInsertOperation tests the insert operation
+func TestInsertOperation(t *testing.T) {
+	mockCollection := new(MockCollection)
+	config := TestingConfig{
+		Threads:  2,
+		DocCount: 10,
+		DropDb:   true,
+	}
+	testType := "insert"
+
+	mockCollection.On("Drop", mock.Anything).Return(nil)
+	mockCollection.On("InsertOne", mock.Anything, mock.Anything).Return(&mongo.InsertOneResult{}, nil)
+
+	runTest(t, mockCollection, testType, config)
+
+	mockCollection.AssertNumberOfCalls(t, "Drop", 1)
+	mockCollection.AssertNumberOfCalls(t, "InsertOne", config.DocCount)
+}
+
+// TestUpdateOperation tests the update operation
+func TestUpdateOperation(t *testing.T) {
+	mockCollection := new(MockCollection)
+	config := TestingConfig{
+		Threads:  2,
+		DocCount: 10,
+	}
+	testType := "update"
+
+	mockCollection.On("UpdateOne", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&mongo.UpdateResult{}, nil)
+
+	runTest(t, mockCollection, testType, config)
+
+	expectedCalls := config.DocCount
+	mockCollection.AssertNumberOfCalls(t, "UpdateOne", expectedCalls)
+}
+
+// TestUpsertOperation tests the upsert operation
+func TestUpsertOperation(t *testing.T) {
+	mockCollection := new(MockCollection)
+	config := TestingConfig{
+		Threads:  2,
+		DocCount: 10,
+		DropDb:   true,
+	}
+	testType := "upsert"
+
+	mockCollection.On("Drop", mock.Anything).Return(nil)
+	mockCollection.On("UpdateOne", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&mongo.UpdateResult{UpsertedCount: 1}, nil)
+
+	runTest(t, mockCollection, testType, config)
+
+	mockCollection.AssertNumberOfCalls(t, "Drop", 1)
+	mockCollection.AssertNumberOfCalls(t, "UpdateOne", config.DocCount)
+}
+
+// TestDeleteOperation tests the delete operation
+func TestDeleteOperation(t *testing.T) {
+	mockCollection := new(MockCollection)
+	config := TestingConfig{
+		Threads:  2,
+		DocCount: 10,
+	}
+	testType := "delete"
+
+	mockCollection.On("DeleteOne", mock.Anything, mock.Anything).Return(&mongo.DeleteResult{DeletedCount: 1}, nil)
+
+	runTest(t, mockCollection, testType, config)
+
+	expectedCalls := config.DocCount
+	mockCollection.AssertNumberOfCalls(t, "DeleteOne", expectedCalls)
+}
+
+// TestInsertOperationWithRetries tests that failed inserts are retried according to the retry policy
+func TestInsertOperationWithRetries(t *testing.T) {
+	t.Chdir(t.TempDir())
+	mockCollection := new(MockCollection)
+	config := TestingConfig{
+		Threads:  1,
+		DocCount: 5,
+		Retry:    RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond},
+	}
+	testType := "insert"
+
+	mockCollection.On("InsertOne", mock.Anything, mock.Anything).Return((*mongo.InsertOneResult)(nil), errors.New("not writable primary")).Twice()
+	mockCollection.On("InsertOne", mock.Anything, mock.Anything).Return(&mongo.InsertOneResult{}, nil)
+
+	runTest(t, mockCollection, testType, config)
+
+	mockCollection.AssertNumberOfCalls(t, "InsertOne", config.DocCount+2)
+}
+
+// TestRetryPolicyStats verifies the retry statistics of succeeded and exhausted operations
+func TestRetryPolicyStats(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond}
+	stats := &RetryStats{}
+
+	attempts := 0
+	err := policy.run(stats, func() error {
+		if attempts++; attempts == 1 {
+			return errors.New("network error")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+
+	err = policy.run(stats, func() error { return errors.New("network error") })
+	assert.Error(t, err)
+
+	assert.Equal(t, int64(1), stats.retried.Load())
+	assert.Equal(t, int64(1), stats.exhausted.Load())
+	assert.Positive(t, stats.retryLatency.Load())
+}
+
+// TestDriverRetries verifies that commands the driver sends again on the same session are counted as retries
+func TestDriverRetries(t *testing.T) {
+	retries := NewDriverRetries()
+	var started int
+	monitor := CombineCommandMonitors(retries.CommandMonitor(), &event.CommandMonitor{
+		Started: func(context.Context, *event.CommandStartedEvent) { started++ },
+	})
+	session := bson.D{{Key: "id", Value: primitive.Binary{Subtype: 4, Data: []byte("0123456789abcdef")}}}
+	requestID := int64(0)
+	send := func(command bson.D, failed bool) {
+		raw, err := bson.Marshal(append(command, bson.E{Key: "lsid", Value: session}))
+		assert.NoError(t, err)
+		requestID++
+		monitor.Started(context.Background(), &event.CommandStartedEvent{Command: raw, CommandName: command[0].Key, RequestID: requestID})
+		finished := event.CommandFinishedEvent{CommandName: command[0].Key, RequestID: requestID}
+		if failed {
+			monitor.Failed(context.Background(), &event.CommandFailedEvent{CommandFinishedEvent: finished})
+		} else {
+			monitor.Succeeded(context.Background(), &event.CommandSucceededEvent{CommandFinishedEvent: finished})
+		}
+	}
+
+	insert := func(txnNumber int64) bson.D {
+		return bson.D{{Key: "insert", Value: "testdata"}, {Key: "txnNumber", Value: txnNumber}}
+	}
+	send(insert(1), true)
+	send(insert(1), false)
+	send(insert(2), false)
+	find := bson.D{{Key: "find", Value: "testdata"}}
+	send(find, true)
+	send(find, false)
+	send(find, false)
+	// Statements of a transaction share its transaction number
+	send(append(insert(3), bson.E{Key: "autocommit", Value: false}), false)
+	send(append(insert(3), bson.E{Key: "autocommit", Value: false}), false)
+
+	assert.Equal(t, 8, started)
+	assert.Equal(t, []string{"1", "1"}, retries.Values())
+	assert.Empty(t, retries.started)
+	retries.begin()
+	assert.Equal(t, []string{"0", "0"}, retries.Values())
+
+	config := TestingConfig{DriverRetries: retries}
+	assert.Equal(t, []MetricsSource{retries}, config.metricsSources(&RetryStats{}))
+}
+
+// TestInsertOperationWithMultipleClients tests that workers are spread across the clients
+func TestInsertOperationWithMultipleClients(t *testing.T) {
+	t.Chdir(t.TempDir())
+	first, second := new(MockCollection), new(MockCollection)
+	config := TestingConfig{
+		Threads:  2,
+		DocCount: 10,
+		DropDb:   true,
+	}
+	testType := "insert"
+
+	first.On("Drop", mock.Anything).Return(nil)
+	first.On("InsertOne", mock.Anything, mock.Anything).Return(&mongo.InsertOneResult{}, nil)
+	second.On("InsertOne", mock.Anything, mock.Anything).Return(&mongo.InsertOneResult{}, nil)
+
+	runTest(t, NewMultiClientCollection([]CollectionAPI{first, second}), testType, config)
+
+	first.AssertNumberOfCalls(t, "Drop", 1)
+	first.AssertNumberOfCalls(t, "InsertOne", config.DocCount/2)
+	second.AssertNumberOfCalls(t, "InsertOne", config.DocCount/2)
+}
+
+// TestAggregateOperation tests the aggregate operation with a pipeline file
+func TestAggregateOperation(t *testing.T) {
+	t.Chdir(t.TempDir())
+	mockCollection := new(MockCollection)
+	pipelineFile := filepath.Join(t.TempDir(), "pipeline.json")
+	err := os.WriteFile(pipelineFile, []byte(`[{"$match": {"rnd": {"$gt": "{{int 0 100}}"}}}, {"$limit": 10}]`), 0o600)
+	assert.NoError(t, err)
+	config := TestingConfig{
+		Threads:      2,
+		DocCount:     4,
+		PipelineFile: pipelineFile,
+	}
+	testType := "aggregate"
+
+	for i := 0; i < config.DocCount; i++ {
+		cursor, err := mongo.NewCursorFromDocuments([]interface{}{bson.M{"rnd": 1}, bson.M{"rnd": 2}}, nil, nil)
+		assert.NoError(t, err)
+		mockCollection.On("Aggregate", mock.Anything, mock.Anything, mock.Anything).Return(cursor, nil).Once()
+	}
+
+	runTest(t, mockCollection, testType, config)
+
+	mockCollection.AssertNumberOfCalls(t, "Aggregate", config.DocCount)
+	pipeline := mockCollection.Calls[0].Arguments.Get(1).(bson.A)
+	match := pipeline[0].(bson.D)[0].Value.(bson.D)[0].Value.(bson.D)[0]
+	assert.Equal(t, "$gt", match.Key)
+	assert.IsType(t, int64(0), match.Value)
+}
+
+// TestScanOperation tests the scan operation
+func TestScanOperation(t *testing.T) {
+	t.Chdir(t.TempDir())
+	mockCollection := new(MockCollection)
+	config := TestingConfig{
+		Threads:   2,
+		DocCount:  4,
+		ScanField: "rnd",
+		ScanMin:   0,
+		ScanMax:   1000,
+		ScanWidth: 10,
+		ScanSort:  -1,
+	}
+	testType := "scan"
+
+	for i := 0; i < config.DocCount; i++ {
+		cursor, err := mongo.NewCursorFromDocuments([]interface{}{bson.M{"rnd": 1}}, nil, nil)
+		assert.NoError(t, err)
+		mockCollection.On("Find", mock.Anything, mock.Anything, mock.Anything).Return(cursor, nil).Once()
+	}
+
+	runTest(t, mockCollection, testType, config)
+
+	mockCollection.AssertNumberOfCalls(t, "Find", config.DocCount)
+	filter := mockCollection.Calls[0].Arguments.Get(1).(bson.D)
+	bounds := filter[0].Value.(bson.D)
+	assert.Equal(t, "rnd", filter[0].Key)
+	assert.Equal(t, int64(10), bounds[1].Value.(int64)-bounds[0].Value.(int64))
+	assert.LessOrEqual(t, bounds[1].Value.(int64), int64(1000))
+
+	// Ranges wider than the int64 values reach do not overflow
+	for _, scanMin := range []int64{-10, math.MinInt64} {
+		scan, err := newScanWorkload(TestingConfig{ScanField: "rnd", ScanMin: scanMin, ScanMax: math.MaxInt64})
+		assert.NoError(t, err)
+		for i := 0; i < 100; i++ {
+			mockCollection := new(MockCollection)
+			cursor, _ := mongo.NewCursorFromDocuments(nil, nil, nil)
+			mockCollection.On("Find", mock.Anything, mock.Anything, mock.Anything).Return(cursor, nil)
+			assert.NoError(t, scan.run(mockCollection, NewRandomizer()))
+			bounds := mockCollection.Calls[0].Arguments.Get(1).(bson.D)[0].Value.(bson.D)
+			lower, upper := bounds[0].Value.(int64), bounds[1].Value.(int64)
+			assert.GreaterOrEqual(t, lower, scanMin)
+			assert.Greater(t, upper, lower)
+			assert.Equal(t, scan.width, uint64(upper)-uint64(lower))
+		}
+	}
+	_, err := newScanWorkload(TestingConfig{ScanField: "rnd", ScanMin: 5, ScanMax: -5})
+	assert.Error(t, err)
+}
+
+// TestTimeSeriesOperations tests that sensor measurements are inserted into a time-series collection and queried in
+// time windows
+func TestTimeSeriesOperations(t *testing.T) {
+	t.Chdir(t.TempDir())
+	mockCollection := new(MockCollection)
+	config := TestingConfig{
+		Threads:    1,
+		DocCount:   4,
+		DropDb:     true,
+		TimeSeries: options.TimeSeries().SetTimeField("ts").SetMetaField("meta"),
+		Sensors:    NewSensorFleet(2, 10*time.Second, "ts", "meta"),
+		WindowSize: time.Minute,
+	}
+
+	mockCollection.On("Drop", mock.Anything).Return(nil)
+	mockCollection.On("CreateCollection", mock.Anything, mock.Anything).Return(nil)
+	mockCollection.On("InsertOne", mock.Anything, mock.Anything).Return(&mongo.InsertOneResult{}, nil)
+
+	runTest(t, mockCollection, "insert", config)
+
+	mockCollection.AssertNumberOfCalls(t, "CreateCollection", 1)
+	first := mockCollection.Calls[2].Arguments.Get(1).(bson.M)
+	second := mockCollection.Calls[3].Arguments.Get(1).(bson.M)
+	third := mockCollection.Calls[4].Arguments.Get(1).(bson.M)
+	assert.Equal(t, "sensor-00000", first["meta"].(bson.M)["id"])
+	assert.Equal(t, "sensor-00001", second["meta"].(bson.M)["id"])
+	elapsed := third["ts"].(time.Time).Sub(first["ts"].(time.Time))
+	assert.InDelta(t, float64(10*time.Second), float64(elapsed), float64(time.Second))
+
+	// The window test places its windows within the time range of the measurements
+	bounds, err := mongo.NewCursorFromDocuments([]interface{}{bson.M{"first": first["ts"], "last": third["ts"]}}, nil, nil)
+	assert.NoError(t, err)
+	mockCollection.On("Aggregate", mock.Anything, mock.Anything, mock.Anything).Return(bounds, nil).Once()
+	for i := 0; i < config.DocCount; i++ {
+		cursor, err := mongo.NewCursorFromDocuments([]interface{}{bson.M{"_id": first["ts"], "avg": 20.5}}, nil, nil)
+		assert.NoError(t, err)
+		mockCollection.On("Aggregate", mock.Anything, mock.Anything, mock.Anything).Return(cursor, nil).Once()
+	}
+
+	result := runTest(t, mockCollection, "window", config)
+
+	assert.Equal(t, int64(config.DocCount), result.Count)
+	pipeline := mockCollection.Calls[len(mockCollection.Calls)-1].Arguments.Get(1).(bson.A)
+	match := pipeline[0].(bson.M)["$match"].(bson.M)
+	assert.Contains(t, match, "meta.id")
+	assert.Contains(t, match, "ts")
+}
+
+// TestQueueOperation tests that the queue test claims and completes items and reports items claimed twice
+func TestQueueOperation(t *testing.T) {
+	t.Chdir(t.TempDir())
+	mockCollection := new(MockCollection)
+	config := TestingConfig{
+		Threads:  2,
+		DocCount: 4,
+	}
+
+	mockCollection.On("CreateIndexes", mock.Anything, mock.Anything).Return([]string{"status_1_seq_1"}, nil)
+	mockCollection.On("InsertMany", mock.Anything, mock.Anything).Return(&mongo.InsertManyResult{}, nil)
+	// The third claim returns an item that was claimed before
+	ids := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()}
+	for _, id := range []primitive.ObjectID{ids[0], ids[1], ids[0], ids[2]} {
+		item := mongo.NewSingleResultFromDocument(bson.M{"_id": id, "claims": 1}, nil, nil)
+		mockCollection.On("FindOneAndUpdate", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(item).Once()
+	}
+	mockCollection.On("UpdateOne", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+	mockCollection.On("CountDocuments", mock.Anything, mock.Anything).Return(int64(0), nil)
+
+	result := runTest(t, mockCollection, "queue", config)
+
+	assert.Equal(t, int64(config.DocCount), result.Count)
+	mockCollection.AssertNumberOfCalls(t, "InsertMany", 1)
+	mockCollection.AssertNumberOfCalls(t, "FindOneAndUpdate", config.DocCount)
+	mockCollection.AssertNumberOfCalls(t, "UpdateOne", config.DocCount)
+	items := mockCollection.Calls[1].Arguments.Get(1).([]interface{})
+	assert.Len(t, items, config.DocCount)
+
+	file, err := os.Open("benchmark_results_queue.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	assert.NoError(t, err)
+	assert.Contains(t, records[0], "duplicate_claims")
+	last := records[len(records)-1]
+	for i, column := range records[0] {
+		if column == "duplicate_claims" {
+			assert.Equal(t, "1", last[i])
+		}
+	}
+}
+
+// TestDeleteManyOperation tests that the deleteMany test deletes every bucket once
+func TestDeleteManyOperation(t *testing.T) {
+	t.Chdir(t.TempDir())
+	mockCollection := new(MockCollection)
+	config := TestingConfig{
+		Threads:  2,
+		DocCount: 5,
+		Buckets:  3,
+	}
+
+	mockCollection.On("CreateIndexes", mock.Anything, mock.Anything).Return([]string{"bucket_1"}, nil)
+	mockCollection.On("DeleteMany", mock.Anything, mock.Anything).Return(&mongo.DeleteResult{DeletedCount: 10}, nil)
+
+	result := runTest(t, mockCollection, "deleteMany", config)
+
+	// Every bucket is deleted once, then the workers stop
+	assert.Equal(t, int64(config.Buckets), result.Count)
+	mockCollection.AssertNumberOfCalls(t, "DeleteMany", config.Buckets)
+	var buckets []int64
+	for _, call := range mockCollection.Calls[1:] {
+		buckets = append(buckets, call.Arguments.Get(1).(bson.M)["bucket"].(int64))
+	}
+	assert.ElementsMatch(t, []int64{0, 1, 2}, buckets)
+
+	doc := newDocument(0, NewRandomizer(), config, nil)
+	assert.Less(t, doc["bucket"], config.Buckets)
+}
+
+// TestUpdateMix verifies the operator and pipeline updates generated from the update file
+func TestUpdateMix(t *testing.T) {
+	updateFile := filepath.Join(t.TempDir(), "updates.json")
+	err := os.WriteFile(updateFile, []byte(`[
+		{"update": {"$set": {"items.$[i].qty": "{{int 0 10}}"}}, "arrayFilters": [{"i.qty": {"$lt": 5}}], "grow": 16},
+		{"weight": 2, "update": [{"$set": {"total": {"$add": ["$total", 1]}}}]},
+		{"weight": 0, "update": {"$unset": {"note": ""}}}
+	]`), 0o600)
+	assert.NoError(t, err)
+	mix, err := LoadUpdateMix(updateFile)
+	assert.NoError(t, err)
+	// A missing weight defaults to 1, a weight of 0 disables the update
+	assert.Len(t, mix.specs, 2)
+	assert.Equal(t, 3, mix.total)
+	r := NewRandomizer()
+
+	mix.total = 1 // pick the operator update only
+	update, opts := mix.next(r, bson.D{{Key: "writtenAt", Value: int64(1)}})
+	operators := update.(bson.D)
+	assert.Equal(t, "$set", operators[0].Key)
+	set := operators[0].Value.(bson.D)
+	assert.Equal(t, "items.$[i].qty", set[0].Key)
+	assert.IsType(t, int64(0), set[0].Value)
+	assert.Equal(t, bson.E{Key: "writtenAt", Value: int64(1)}, set[1])
+	assert.Equal(t, "$push", operators[1].Key)
+	assert.Len(t, operators[1].Value.(bson.D)[0].Value, 16)
+	assert.Len(t, opts.ArrayFilters.Filters, 1)
+
+	mix.specs = mix.specs[1:]
+	update, _ = mix.next(r, nil)
+	assert.Len(t, update.(bson.A), 1)
+
+	err = os.WriteFile(updateFile, []byte(`[{"weight": 0, "update": {"$unset": {"note": ""}}}]`), 0o600)
+	assert.NoError(t, err)
+	_, err = LoadUpdateMix(updateFile)
+	assert.Error(t, err)
+}
+
+// TestReplaceOperation tests that the replace test replaces whole documents with payloads within the configured sizes
+func TestReplaceOperation(t *testing.T) {
+	t.Chdir(t.TempDir())
+	mockCollection := new(MockCollection)
+	config := TestingConfig{
+		Threads:         2,
+		DocCount:        10,
+		ReplaceMinBytes: 100,
+		ReplaceMaxBytes: 200,
+	}
+
+	mockCollection.On("ReplaceOne", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+
+	runTest(t, mockCollection, "replace", config)
+
+	mockCollection.AssertNumberOfCalls(t, "ReplaceOne", config.DocCount)
+	for _, call := range mockCollection.Calls {
+		payload := call.Arguments.Get(2).(bson.M)["payload"].([]byte)
+		assert.GreaterOrEqual(t, len(payload), 100)
+		assert.LessOrEqual(t, len(payload), 200)
+	}
+}
+
+// TestLoadIndexes verifies parsing the declared index types
+func TestLoadIndexes(t *testing.T) {
+	indexFile := filepath.Join(t.TempDir(), "indexes.json")
+	err := os.WriteFile(indexFile, []byte(`[
+		{"key": {"rnd": 1}, "unique": true},
+		{"key": {"threadRunCount": 1, "rnd": -1}, "name": "thread_rnd"},
+		{"key": {"updatedAt": 1}, "expireAfterSeconds": 3600, "partialFilterExpression": {"v": {"$gt": 0}}},
+		{"key": {"rnd": "hashed"}},
+		{"key": {"$**": 1}, "wildcardProjection": {"data": 0}}
+	]`), 0o600)
+	assert.NoError(t, err)
+
+	indexes, err := LoadIndexes(indexFile)
+	assert.NoError(t, err)
+	assert.Len(t, indexes, 5)
+	assert.Equal(t, "rnd_1", *indexes[0].Options.Name)
+	assert.True(t, *indexes[0].Options.Unique)
+	assert.Equal(t, "thread_rnd", *indexes[1].Options.Name)
+	assert.Equal(t, int32(3600), *indexes[2].Options.ExpireAfterSeconds)
+	assert.NotNil(t, indexes[2].Options.PartialFilterExpression)
+	assert.Equal(t, "rnd_hashed", *indexes[3].Options.Name)
+	assert.NotNil(t, indexes[4].Options.WildcardProjection)
+}
+
+// TestIndexImpact tests that the insert test runs once per number of declared indexes
+func TestIndexImpact(t *testing.T) {
+	t.Chdir(t.TempDir())
+	mockCollection := new(MockCollection)
+	config := TestingConfig{
+		Threads:  2,
+		DocCount: 10,
+		DropDb:   true,
+		Stop:     StopConditions{MaxOps: 10},
+		Indexes: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "rnd", Value: 1}}, Options: options.Index().SetName("rnd_1")},
+			{Keys: bson.D{{Key: "v", Value: 1}}, Options: options.Index().SetName("v_1")},
+		},
+	}
+
+	mockCollection.On("Drop", mock.Anything).Return(nil)
+	mockCollection.On("DropIndexes", mock.Anything).Return(nil)
+	mockCollection.On("CreateIndexes", mock.Anything, mock.Anything).Return([]string{"rnd_1"}, nil)
+	mockCollection.On("InsertOne", mock.Anything, mock.Anything).Return(&mongo.InsertOneResult{}, nil)
+
+	results, err := Runner{}.RunIndexImpact(mockCollection, "insert", config)
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+
+	mockCollection.AssertNumberOfCalls(t, "Drop", 3)
+	mockCollection.AssertNumberOfCalls(t, "DropIndexes", 3)
+	mockCollection.AssertNumberOfCalls(t, "CreateIndexes", 2)
+	mockCollection.AssertNumberOfCalls(t, "InsertOne", 3*config.DocCount)
+	assert.Len(t, mockCollection.Calls[len(mockCollection.Calls)-config.DocCount-1].Arguments.Get(1), 2)
+	assert.FileExists(t, "index_impact_insert.csv")
+	assert.FileExists(t, "benchmark_results_insert_2idx.csv")
+}
+
+// TestIndexBuildUnderLoad tests that operations are attributed to the phases around an index build
+func TestIndexBuildUnderLoad(t *testing.T) {
+	t.Chdir(t.TempDir())
+	mockCollection := new(MockCollection)
+	config := TestingConfig{
+		Threads:         2,
+		DropDb:          true,
+		Stop:            StopConditions{MaxDuration: time.Second},
+		IndexBuild:      []mongo.IndexModel{{Keys: bson.D{{Key: "rnd", Value: 1}}, Options: options.Index().SetName("rnd_1")}},
+		IndexBuildDelay: 200 * time.Millisecond,
+	}
+
+	mockCollection.On("Drop", mock.Anything).Return(nil)
+	mockCollection.On("CreateIndexes", mock.Anything, mock.Anything).Return([]string{"rnd_1"}, nil).After(300 * time.Millisecond)
+	mockCollection.On("InsertOne", mock.Anything, mock.Anything).Return(&mongo.InsertOneResult{}, nil).After(time.Millisecond)
+
+	runTest(t, mockCollection, "insert", config)
+
+	mockCollection.AssertNumberOfCalls(t, "CreateIndexes", 1)
+	file, err := os.Open("index_build_insert.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 4)
+	assert.Equal(t, []string{"before", "during", "after"}, []string{records[1][0], records[2][0], records[3][0]})
+	buildDuration, err := strconv.ParseFloat(records[2][2], 64)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.3, buildDuration, 0.1)
+	assert.Equal(t, "completed", records[2][8])
+
+	// A build outlasting the workload ends its phase with the workload, and a failed build fails the test
+	failing := new(MockCollection)
+	failing.On("Drop", mock.Anything).Return(nil)
+	failing.On("CreateIndexes", mock.Anything, mock.Anything).Return([]string{}, errors.New("index build aborted")).After(600 * time.Millisecond)
+	failing.On("InsertOne", mock.Anything, mock.Anything).Return(&mongo.InsertOneResult{}, nil).After(time.Millisecond)
+	config.Stop = StopConditions{MaxDuration: 400 * time.Millisecond}
+
+	_, err = Runner{}.run(failing, "insert", config, fetchDocumentIDsMock)
+	assert.ErrorContains(t, err, "index build aborted")
+	file, err = os.Open("index_build_insert.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+	records, err = csv.NewReader(file).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+	buildDuration, err = strconv.ParseFloat(records[2][2], 64)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.2, buildDuration, 0.1)
+	assert.Equal(t, "failed: index build aborted", records[2][8])
+}
+
+// TestDurationDeleteOperation tests that duration deletes stop once the pool is empty, unless it is refilled, and that
+// deletes without effect are not counted
+func TestDurationDeleteOperation(t *testing.T) {
+	t.Chdir(t.TempDir())
+	mockCollection := new(MockCollection)
+	config := TestingConfig{
+		Threads:  2,
+		DocCount: 10,
+		Stop:     StopConditions{MaxDuration: time.Second},
+	}
+
+	mockCollection.On("DeleteOne", mock.Anything, mock.Anything).Return(&mongo.DeleteResult{DeletedCount: 1}, nil)
+
+	result := runTest(t, mockCollection, "delete", config)
+
+	assert.Equal(t, int64(10), result.Count)
+	mockCollection.AssertNumberOfCalls(t, "DeleteOne", 10)
+
+	refilled := new(MockCollection)
+	refilled.On("InsertOne", mock.Anything, mock.Anything).Return(&mongo.InsertOneResult{}, nil).After(time.Millisecond)
+	refilled.On("DeleteOne", mock.Anything, mock.Anything).Return(&mongo.DeleteResult{DeletedCount: 1}, nil)
+	config.RefillThreads = 1
+
+	result = runTest(t, refilled, "delete", config)
+
+	assert.Greater(t, result.Count, int64(10))
+
+	// Deletes of documents deleted by someone else do not count against the operation limit
+	raced := new(MockCollection)
+	raced.On("DeleteOne", mock.Anything, mock.Anything).Return(&mongo.DeleteResult{DeletedCount: 0}, nil).Times(5)
+	raced.On("DeleteOne", mock.Anything, mock.Anything).Return(&mongo.DeleteResult{DeletedCount: 1}, nil)
+
+	result = runTest(t, raced, "delete", TestingConfig{Threads: 1, DocCount: 10, Stop: StopConditions{MaxOps: 5}})
+
+	assert.Equal(t, int64(5), result.Count)
+	raced.AssertNumberOfCalls(t, "DeleteOne", 10)
+}
+
+// TestRunErrors verifies that the runner returns errors instead of ending the process, also when saving its results
+func TestRunErrors(t *testing.T) {
+	t.Chdir(t.TempDir())
+	config := TestingConfig{Threads: 1, DocCount: 10}
+
+	_, err := Runner{}.Run(new(MockCollection), "insert", config)
+	assert.ErrorContains(t, err, "no stop condition")
+
+	config.Stop.MaxOps = 10
+	_, err = Runner{}.Run(new(MockCollection), "unknown", config)
+	assert.ErrorContains(t, err, `unknown test type "unknown"`)
+
+	mockCollection := new(MockCollection)
+	mockCollection.On("Aggregate", mock.Anything, mock.Anything, mock.Anything).Return((*mongo.Cursor)(nil), errors.New("no documents"))
+	_, err = Runner{}.Run(mockCollection, "update", config)
+	assert.ErrorContains(t, err, "no documents")
+
+	// A test whose change streams fail to open is torn down, and both errors are returned
+	queue := new(MockCollection)
+	queue.On("CreateIndexes", mock.Anything, mock.Anything).Return([]string{"status_1_seq_1"}, nil)
+	queue.On("InsertMany", mock.Anything, mock.Anything).Return(&mongo.InsertManyResult{}, nil)
+	queue.On("Watch", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("change streams unsupported"))
+	queue.On("CountDocuments", mock.Anything, mock.Anything).Return(int64(0), errors.New("count failed"))
+	watched := config
+	watched.Watchers = 1
+	_, err = Runner{}.Run(queue, "queue", watched)
+	assert.ErrorContains(t, err, "change streams unsupported")
+	assert.ErrorContains(t, err, "count failed")
+
+	// The results are saved to the output directory, unless saving is disabled
+	config.Output = Output{Dir: "results"}
+	_, err = Runner{}.Run(NewMemoryCollection(0, 0), "insert", config)
+	assert.ErrorContains(t, err, "failed to create CSV file")
+	assert.NoError(t, os.Mkdir("results", 0o700))
+	_, err = Runner{}.Run(NewMemoryCollection(0, 0), "insert", config)
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join("results", "benchmark_results_insert.csv"))
+
+	config.Output = Output{Disabled: true}
+	_, err = Runner{}.Run(NewMemoryCollection(0, 0), "upsert", config)
+	assert.NoError(t, err)
+	assert.NoFileExists(t, "benchmark_results_upsert.csv")
+}
+
+// TestMemoryCollection runs the strategies end to end against the in-memory collection
+func TestMemoryCollection(t *testing.T) {
+	t.Chdir(t.TempDir())
+	collection := NewMemoryCollection(0, 0)
+	config := TestingConfig{Threads: 4, DocCount: 100, DropDb: true, Stop: StopConditions{MaxOps: 100}}
+
+	for _, testType := range []string{"insert", "update", "upsert"} {
+		result, err := Runner{}.Run(collection, testType, config)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(100), result.Count)
+	}
+	// The upsert test starts from an empty collection and picks from the first half of its IDs
+	count, err := collection.CountDocuments(context.Background(), bson.M{})
+	assert.NoError(t, err)
+	assert.Greater(t, count, int64(0))
+	assert.LessOrEqual(t, count, int64(50))
+
+	result, err := Runner{}.Run(collection, "delete", config)
+	assert.NoError(t, err)
+	assert.Equal(t, count, result.Count)
+	count, _ = collection.CountDocuments(context.Background(), bson.M{})
+	assert.Equal(t, int64(0), count)
+
+	id := primitive.NewObjectID()
+	_, err = collection.InsertOne(context.Background(), bson.M{"_id": id, "rnd": int64(1)})
+	assert.NoError(t, err)
+	_, err = collection.InsertOne(context.Background(), bson.M{"_id": id})
+	assert.True(t, mongo.IsDuplicateKeyError(err))
+	updated, err := collection.UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": bson.M{"rnd": 2}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), updated.ModifiedCount)
+	count, _ = collection.CountDocuments(context.Background(), bson.M{"rnd": int64(2)})
+	assert.Equal(t, int64(1), count)
+	_, err = collection.UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$inc": bson.M{"rnd": 1}})
+	assert.Error(t, err)
+
+	// Operator filters are rejected rather than matching nothing
+	_, err = collection.Find(context.Background(), bson.M{"rnd": bson.M{"$gte": 1}})
+	assert.ErrorContains(t, err, "$gte operator is not supported")
+	_, err = collection.CountDocuments(context.Background(), bson.D{{Key: "$or", Value: bson.A{bson.M{"rnd": 1}}}})
+	assert.ErrorContains(t, err, "$or operator")
+	_, err = collection.DeleteMany(context.Background(), bson.M{"rnd": bson.D{{Key: "$lt", Value: 5}}})
+	assert.ErrorContains(t, err, "$lt operator")
+	// Tests whose operations the collection cannot serve fail before they start
+	_, err = Runner{}.Run(collection, "scan", TestingConfig{Threads: 1, ScanField: "rnd", ScanMin: 0, ScanMax: 10, ScanWidth: 5, Stop: StopConditions{MaxOps: 1}})
+	assert.EqualError(t, err, "scan test is not supported by the in-memory collection")
+	pipelineFile := filepath.Join(t.TempDir(), "pipeline.json")
+	assert.NoError(t, os.WriteFile(pipelineFile, []byte(`[[{"$match": {"rnd": 1}}, {"$limit": 5}], [{"$group": {"_id": "$rnd"}}]]`), 0o600))
+	_, err = Runner{}.Run(collection, "aggregate", TestingConfig{Threads: 1, PipelineFile: pipelineFile, Stop: StopConditions{MaxOps: 1}})
+	assert.EqualError(t, err, "$group stage is not supported by the in-memory collection")
+	assert.NoError(t, os.WriteFile(pipelineFile, []byte(`[{"$match": {"rnd": 1}}, {"$sample": {"size": 5}}]`), 0o600))
+	result, err = Runner{}.Run(collection, "aggregate", TestingConfig{Threads: 1, PipelineFile: pipelineFile, Stop: StopConditions{MaxOps: 1}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), result.Count)
+
+	// The queue test fails before enqueuing items it could never claim
+	_, err = Runner{}.Run(NewFaultyCollection(collection, NewFaultInjector(nil)), "queue", TestingConfig{Threads: 1, QueueItems: 10, Stop: StopConditions{MaxOps: 10}})
+	assert.EqualError(t, err, "queue test is not supported by the in-memory collection")
+	count, _ = collection.CountDocuments(context.Background(), bson.M{"status": "pending"})
+	assert.Equal(t, int64(0), count)
+
+	failing := NewMemoryCollection(time.Millisecond, 1)
+	result, err = Runner{}.Run(failing, "insert", TestingConfig{Threads: 2, DocCount: 10, Stop: StopConditions{MaxOps: 10}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), result.Count)
+}
+
+// TestFaultyCollection verifies that injected faults fail, delay and stall operations as declared in the fault file
+func TestFaultyCollection(t *testing.T) {
+	faultFile := filepath.Join(t.TempDir(), "faults.json")
+	err := os.WriteFile(faultFile, []byte(`[
+		{"kind": "error", "error": "NotWritablePrimary", "everySec": 10, "forSec": 2},
+		{"kind": "error", "code": 50, "probability": 0.5},
+		{"kind": "latency", "latencyMs": 20}
+	]`), 0o600)
+	assert.NoError(t, err)
+	faults, err := LoadFaults(faultFile)
+	assert.NoError(t, err)
+	assert.Len(t, faults, 3)
+
+	active, remaining := faults[0].window(5 * time.Second)
+	assert.False(t, active)
+	active, remaining = faults[0].window(9 * time.Second)
+	assert.True(t, active)
+	assert.Equal(t, time.Second, remaining)
+	active, _ = faults[0].window(15 * time.Second)
+	assert.False(t, active)
+
+	_ = os.WriteFile(faultFile, []byte(`[{"kind": "stall", "probability": 1}]`), 0o600)
+	_, err = LoadFaults(faultFile)
+	assert.Error(t, err)
+
+	memory := NewMemoryCollection(0, 0)
+	failing := NewFaultyCollection(memory, NewFaultInjector([]Fault{{Kind: "error", Probability: 1, Err: faultErrors["NotWritablePrimary"]}}))
+	_, err = failing.InsertOne(context.Background(), bson.M{"_id": 1})
+	var commandErr mongo.CommandError
+	assert.ErrorAs(t, err, &commandErr)
+	assert.Equal(t, int32(10107), commandErr.Code)
+	assert.True(t, commandErr.HasErrorLabel("RetryableWriteError"))
+	count, _ := memory.CountDocuments(context.Background(), bson.M{})
+	assert.Equal(t, int64(0), count)
+	network := NewFaultyCollection(memory, NewFaultInjector([]Fault{{Kind: "error", Probability: 1, Err: faultErrors["network"]}}))
+	_, err = network.DeleteOne(context.Background(), bson.M{})
+	assert.True(t, mongo.IsNetworkError(err))
+
+	injector := NewFaultInjector([]Fault{{Kind: "latency", Probability: 1, Latency: 20 * time.Millisecond}})
+	slow := NewFaultyCollection(NewMultiClientCollection([]CollectionAPI{memory, memory}), injector)
+	start := time.Now()
+	_, err = collectionForWorker(slow, 1).InsertOne(context.Background(), bson.M{"_id": 2})
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	assert.Equal(t, []string{"1", "0", "0"}, injector.Values())
+
+	// Setup and verification are not affected, only the operations of the workers fail
+	t.Chdir(t.TempDir())
+	config := TestingConfig{Threads: 2, DocCount: 10, Verify: true, Stop: StopConditions{MaxOps: 10}}
+	_, err = Runner{}.Run(memory, "insert", config)
+	assert.NoError(t, err)
+	result, err := Runner{}.Run(failing, "update", config)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), result.Count)
+	assert.Equal(t, int64(0), result.Verification.Lost)
+
+	// The schedule starts with the test: the fault would be active by now if it started with the injector
+	scheduled := NewFaultInjector([]Fault{{Kind: "error", Probability: 1, Every: time.Second, For: 500 * time.Millisecond, Err: faultErrors["WriteConflict"]}})
+	time.Sleep(600 * time.Millisecond)
+	config.Stop = StopConditions{MaxDuration: 200 * time.Millisecond}
+	result, err = Runner{}.Run(NewFaultyCollection(memory, scheduled), "insert", config)
+	assert.NoError(t, err)
+	assert.Positive(t, result.Count)
+	assert.Equal(t, "0", scheduled.Values()[1])
+}
+
+// TestVerification verifies that the written documents are read back after each test and lost and phantom writes
+// are reported
+func TestVerification(t *testing.T) {
+	t.Chdir(t.TempDir())
+	collection := NewMemoryCollection(0, 0)
+	config := TestingConfig{Threads: 4, DocCount: 100, DropDb: true, Verify: true, Stop: StopConditions{MaxOps: 100}}
+	for _, testType := range []string{"insert", "update", "delete", "upsert"} {
+		result, err := Runner{}.Run(collection, testType, config)
+		assert.NoError(t, err)
+		if assert.NotNil(t, result.Verification) {
+			assert.True(t, result.Verification.Passed(), testType)
+			assert.Greater(t, result.Verification.Checked, int64(0), testType)
+		}
+	}
+
+	faulty := NewFaultyCollection(collection, NewFaultInjector([]Fault{{Kind: "error", Probability: 0.2, Err: faultErrors["network"]}}))
+	result, err := Runner{}.Run(faulty, "insert", config)
+	assert.NoError(t, err)
+	assert.True(t, result.Verification.Passed())
+	assert.Equal(t, int64(100), result.Verification.Checked)
+	assert.Equal(t, 100-result.Count, result.Verification.Uncertain)
+
+	_ = collection.Drop(context.Background())
+	writes := newWriteLog(true)
+	lost, stale, resurrected, failed := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	writes.Wrote(lost, bson.M{"rnd": int64(1)}, nil)
+	writes.Updated(stale, bson.M{"$set": bson.M{"rnd": int64(2)}}, nil)
+	writes.Deleted(resurrected, nil)
+	writes.Wrote(failed, bson.M{"rnd": int64(4)}, errors.New("timeout"))
+	// Updates of an update mix are documents in order; the rnd value is only known if they set it
+	mixed, incremented := primitive.NewObjectID(), primitive.NewObjectID()
+	writes.Updated(mixed, bson.D{{Key: "$set", Value: bson.D{{Key: "rnd", Value: int64(5)}}}, {Key: "$inc", Value: bson.M{"v": 1}}}, nil)
+	writes.Updated(incremented, bson.D{{Key: "$inc", Value: bson.M{"rnd": 1}}}, nil)
+	docs := []bson.M{{"_id": stale, "rnd": int64(1)}, {"_id": resurrected}, {"_id": failed, "rnd": int64(4)}, {"_id": "unknown"},
+		{"_id": mixed, "rnd": int64(6)}, {"_id": incremented, "rnd": int64(7)}}
+	for _, doc := range docs {
+		_, err = collection.InsertOne(context.Background(), doc)
+		assert.NoError(t, err)
+	}
+	verification, err := writes.verify(collection)
+	assert.NoError(t, err)
+	assert.Equal(t, Verification{Checked: 6, Lost: 3, Phantom: 2, Uncertain: 1, Unverified: 1}, verification)
+}
+
+// TestStaleReads verifies that reads older than an acknowledged write are found in the history of the staleReads test
+func TestStaleReads(t *testing.T) {
+	t.Chdir(t.TempDir())
+	configs, err := ParseReadConfigs("majority:secondary, :nearest")
+	assert.NoError(t, err)
+	assert.Equal(t, []ReadConfig{{Concern: "majority", Preference: "secondary"}, {Preference: "nearest"}}, configs)
+	_, err = ParseReadConfigs("eventual:primary")
+	assert.Error(t, err)
+	_, err = ParseReadConfigs("local:everywhere")
+	assert.Error(t, err)
+
+	config := TestingConfig{Threads: 4, DocCount: 400, DropDb: true, ReadConfigs: configs, StaleReadKeys: 10, Stop: StopConditions{MaxOps: 400}}
+	result, err := Runner{}.Run(NewMemoryCollection(0, 0), "staleReads", config)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(400), result.Count)
+	assert.FileExists(t, "stale_reads.csv")
+
+	workload, err := newStaleReadWorkload(WorkloadEnv{Config: TestingConfig{Threads: 1, ReadConfigs: configs, StaleReadKeys: 1}})
+	assert.NoError(t, err)
+	w := workload.(*staleReadWorkload)
+	start := time.Now()
+	w.writes[0] = []acknowledgedWrite{{version: 1, acked: start}, {version: 2, acked: start.Add(10 * time.Millisecond)}}
+	w.workers[0].reads = []observedRead{
+		{config: 0, version: 0, invoked: start.Add(-time.Millisecond)},
+		{config: 0, version: 0, invoked: start.Add(5 * time.Millisecond)},
+		{config: 1, version: 1, invoked: start.Add(20 * time.Millisecond)},
+		{config: 1, version: 2, invoked: start.Add(20 * time.Millisecond)},
+	}
+	stats := w.check()
+	assert.Equal(t, staleReadStats{reads: 2, stale: 1, staleness: 5 * time.Millisecond, maxStaleness: 5 * time.Millisecond}, stats[0])
+	assert.Equal(t, staleReadStats{reads: 2, stale: 1, staleness: 10 * time.Millisecond, maxStaleness: 10 * time.Millisecond}, stats[1])
+
+	// An unset read share defaults to 0.8, an explicit 0 only writes, and shares outside 0..1 are rejected
+	assert.Equal(t, 0.8, w.readShare)
+	for share, valid := range map[float64]bool{0: true, 1: true, -0.1: false, 1.5: false} {
+		workload, err = newStaleReadWorkload(WorkloadEnv{Config: TestingConfig{Threads: 1, StaleReadKeys: 1, ReadShare: &share}})
+		if valid {
+			assert.NoError(t, err)
+			assert.Equal(t, share, workload.(*staleReadWorkload).readShare)
+		} else {
+			assert.Error(t, err)
+		}
+	}
+}
+
+// TestCausalReads verifies that the causalReads test alternates between reads in sessions and plain reads
+func TestCausalReads(t *testing.T) {
+	t.Chdir(t.TempDir())
+	config := TestingConfig{Threads: 4, DocCount: 200, DropDb: true, Stop: StopConditions{MaxOps: 200}}
+	workload, err := newCausalReadWorkload(WorkloadEnv{Config: config, RetryStats: &RetryStats{}})
+	assert.NoError(t, err)
+	assert.Equal(t, ReadConfig{Concern: "majority", Preference: "secondary"}, workload.(*causalReadWorkload).config)
+
+	result, err := Runner{}.Run(NewMemoryCollection(0, 0), "causalReads", config)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(200), result.Count)
+
+	config.ReadConfigs = []ReadConfig{{}, {Preference: "nearest"}}
+	_, err = Runner{}.Run(NewMemoryCollection(0, 0), "causalReads", config)
+	assert.Error(t, err)
+}
+
+// TestFailoverTracker verifies that a primary change during a test is reported once with the unavailability it caused,
+// however many clients saw it
+func TestFailoverTracker(t *testing.T) {
+	t.Chdir(t.TempDir())
+	tracker := NewFailoverTracker()
+	monitor := tracker.ServerMonitor()
+	// Two clients share the monitor and report every change
+	clients := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID()}
+	primary := func(addr string) {
+		topology := description.Topology{Servers: []description.Server{{Addr: address.Address("a:27017"), Kind: description.RSSecondary}}}
+		if addr != "" {
+			topology.Servers = append(topology.Servers, description.Server{Addr: address.Address(addr), Kind: description.RSPrimary})
+		}
+		for _, client := range clients {
+			monitor.TopologyDescriptionChanged(&event.TopologyDescriptionChangedEvent{TopologyID: client, NewDescription: topology})
+		}
+	}
+	primary("b:27017")
+	for _, client := range clients {
+		monitor.ServerDescriptionChanged(&event.ServerDescriptionChangedEvent{Address: address.Address("a:27017"), TopologyID: client,
+			PreviousDescription: description.Server{Kind: description.RSPrimary}, NewDescription: description.Server{Kind: description.RSSecondary}})
+	}
+	assert.Len(t, tracker.timeline, 2)
+
+	// Writes fail with NotWritablePrimary from 1.5 s to 2 s, while the primary steps down and another one is elected
+	faults := NewFaultInjector([]Fault{{Kind: "error", Probability: 1, Every: 2 * time.Second, For: 500 * time.Millisecond, Err: faultErrors["NotWritablePrimary"]}})
+	collection := NewFaultyCollection(NewMemoryCollection(time.Millisecond, 0), faults)
+	time.AfterFunc(1600*time.Millisecond, func() { primary("") })
+	time.AfterFunc(1900*time.Millisecond, func() { primary("c:27017") })
+	config := TestingConfig{Threads: 2, DropDb: true, Failover: tracker, Stop: StopConditions{MaxDuration: 2500 * time.Millisecond}}
+	result, err := Runner{}.Run(collection, "insert", config)
+	assert.NoError(t, err)
+	if assert.NotNil(t, result.Failover) {
+		assert.Equal(t, 2, result.Failover.PrimaryChanges)
+		assert.Greater(t, result.Failover.FailedOps, int64(0))
+		assert.GreaterOrEqual(t, result.Failover.Unavailable, 400*time.Millisecond)
+	}
+	assert.FileExists(t, "failover_insert.csv")
+	assert.Equal(t, "c:27017", tracker.Values()[0])
+
+	// A client that still follows an old primary does not change the primary of the replica set
+	tracker.topologyChanged(clients[0], "none")
+	tracker.topologyChanged(clients[0], "d:27017")
+	tracker.topologyChanged(clients[1], "none")
+	tracker.topologyChanged(clients[1], "d:27017")
+	assert.Equal(t, []string{"d:27017", "primary changed from c:27017 to none | primary changed from none to d:27017"}, tracker.Values())
+
+	result, err = Runner{}.Run(NewMemoryCollection(0, 0), "insert", TestingConfig{Threads: 2, DropDb: true, Failover: tracker, Stop: StopConditions{MaxOps: 10}})
+	assert.NoError(t, err)
+	assert.Nil(t, result.Failover)
+}
+
+// countingWorkload counts its operations and fails every fifth
+type countingWorkload struct {
+	BaseWorkload
+	ops      atomic.Int64
+	tornDown bool
+}
+
+func (w *countingWorkload) Run(_ CollectionAPI, _ *Worker) error {
+	if w.ops.Add(1)%5 == 0 {
+		return errors.New("fifth operation")
+	}
+	return nil
+}
+
+func (w *countingWorkload) Teardown(CollectionAPI) error {
+	w.tornDown = true
+	return nil
+}
+
+// TestRegisteredWorkload verifies that the runner runs a registered workload selected by its name
+func TestRegisteredWorkload(t *testing.T) {
+	t.Chdir(t.TempDir())
+	workload := &countingWorkload{}
+	RegisterWorkload(WorkloadDefinition{Name: "counting", New: func(WorkloadEnv) (Workload, error) {
+		return workload, nil
+	}})
+	t.Cleanup(func() { delete(workloads, "counting") })
+	config := TestingConfig{Threads: 2, DocCount: 20}
+
+	result := runTest(t, new(MockCollection), "counting", config)
+
+	assert.Equal(t, int64(20), workload.ops.Load())
+	assert.Equal(t, int64(16), result.Count)
+	assert.True(t, workload.tornDown)
+	assert.Contains(t, WorkloadNames(), "insert")
+}
+
+// TestStopConditions verifies that a test stops on whichever stop condition is reached first
+func TestStopConditions(t *testing.T) {
+	t.Chdir(t.TempDir())
+	mockCollection := new(MockCollection)
+	config := TestingConfig{
+		Threads:  2,
+		DocCount: 10,
+		Stop:     StopConditions{MaxOps: 1000000, MaxDuration: 10 * time.Second, MaxErrors: 5},
+	}
+
+	mockCollection.On("InsertOne", mock.Anything, mock.Anything).Return((*mongo.InsertOneResult)(nil), errors.New("not primary"))
+
+	result := runTest(t, mockCollection, "insert", config)
+
+	assert.Equal(t, int64(0), result.Count)
+	assert.Equal(t, "error budget of 5 failed operations exhausted", result.StopReason)
+	assert.Less(t, len(mockCollection.Calls), 10)
+
+	stop := newStopper(StopConditions{LatencySLO: 10 * time.Millisecond, SLOWindow: 2}, 1)
+	stop.observeInterval(20 * time.Millisecond)
+	stop.observeInterval(5 * time.Millisecond)
+	stop.observeInterval(20 * time.Millisecond)
+	assert.True(t, stop.next(0))
+	stop.observeInterval(20 * time.Millisecond)
+	assert.False(t, stop.next(0))
+	assert.Equal(t, "p99 latency above the SLO of 10ms for 2 seconds", stop.finish())
+}
+
+// TestTemplatePlaceholders verifies that placeholders are replaced with generated values
+func TestTemplatePlaceholders(t *testing.T) {
+	template, err := CompileTemplate(bson.D{
+		{Key: "n", Value: "{{int 5 5}}"},
+		{Key: "s", Value: "{{string 8}}"},
+		{Key: "p", Value: "{{pick a}}"},
+		{Key: "a", Value: bson.A{"{{float 1 1}}", "literal"}},
+	})
+	assert.NoError(t, err)
+
+	doc := template.Generate(NewRandomizer()).(bson.D)
+	assert.Equal(t, int64(5), doc[0].Value)
+	assert.Len(t, doc[1].Value, 8)
+	assert.Equal(t, "a", doc[2].Value)
+	assert.Equal(t, bson.A{1.0, "literal"}, doc[3].Value)
+
+	_, err = CompileTemplate(bson.D{{Key: "x", Value: "{{unknown}}"}})
+	assert.Error(t, err)
+	_, err = CompileTemplate(bson.D{{Key: "x", Value: "{{int 9 1}}"}})
+	assert.Error(t, err)
+
+	// Integer bounds are exact and ranges wider than an int64 can hold do not overflow
+	for _, placeholder := range []string{"{{int -9223372036854775808 9223372036854775807}}", "{{int -1 9223372036854775807}}"} {
+		template, err := CompileTemplate(bson.D{{Key: "n", Value: placeholder}})
+		assert.NoError(t, err)
+		for i := 0; i < 100; i++ {
+			assert.NotPanics(t, func() { template.Generate(NewRandomizer()) })
+		}
+	}
+	template, err = CompileTemplate(bson.D{{Key: "n", Value: "{{int 9007199254740993 9007199254740993}}"}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9007199254740993), template.Generate(NewRandomizer()).(bson.D)[0].Value)
+}
+
+// TestCountDocuments verifies the CountDocuments method in isolation
+func TestCountDocuments(t *testing.T) {
+	mockCollection := new(MockCollection)
+
+	mockCollection.On("CountDocuments", mock.Anything, mock.Anything).Return(int64(10), nil)
+
+	count, err := mockCollection.CountDocuments(context.Background(), bson.M{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), count)
+	mockCollection.AssertExpectations(t)
+}
+
+// fakeCommandRunner answers serverStatus and collStats with increasing counters
+type fakeCommandRunner struct {
+	calls int64
+}
+
+func (f *fakeCommandRunner) RunCommand(_ context.Context, runCommand interface{}, _ ...*options.RunCmdOptions) *mongo.SingleResult {
+	if runCommand.(bson.D)[0].Key == "collStats" {
+		return mongo.NewSingleResultFromDocument(bson.M{"count": int32(42), "nindexes": int32(1)}, nil, nil)
+	}
+	f.calls++
+	return mongo.NewSingleResultFromDocument(bson.M{
+		"opcounters":  bson.M{"insert": f.calls * 100},
+		"connections": bson.M{"current": int32(7)},
+		"wiredTiger":  bson.M{"concurrentTransactions": bson.M{"write": bson.M{"out": int32(3)}}},
+	}, nil, nil)
+}
+
+// TestServerStatsSampler verifies that counters are reported as rates and gauges as sampled values
+func TestServerStatsSampler(t *testing.T) {
+	sampler := NewServerStatsSampler(&fakeCommandRunner{}, "testdata", time.Second)
+	sampler.sample()
+	sampler.previousTime = sampler.previousTime.Add(-time.Second)
+	sampler.sample()
+
+	assert.Len(t, sampler.records, 3)
+	column := func(name string) int {
+		for i, header := range sampler.records[0] {
+			if header == name {
+				return i
+			}
+		}
+		t.Fatalf("missing column %s", name)
+		return -1
+	}
+	assert.Equal(t, "", sampler.records[1][column("insert_per_sec")])
+	rate, err := strconv.ParseFloat(sampler.records[2][column("insert_per_sec")], 64)
+	assert.NoError(t, err)
+	assert.InDelta(t, 100, rate, 1)
+	assert.Equal(t, "7", sampler.records[2][column("connections_current")])
+	assert.Equal(t, "3", sampler.records[2][column("write_tickets_out")])
+	assert.Equal(t, "42", sampler.records[2][column("coll_count")])
+	assert.Equal(t, "", sampler.records[2][column("cache_bytes")])
+}
+
+// TestParseReplicationLag verifies the member and majority commit point lag computed from replSetGetStatus
+func TestParseReplicationLag(t *testing.T) {
+	now := time.Now()
+	status := bson.M{
+		"members": bson.A{
+			bson.M{"name": "rs0:27017", "stateStr": "PRIMARY", "optimeDate": primitive.NewDateTimeFromTime(now)},
+			bson.M{"name": "rs1:27017", "stateStr": "SECONDARY", "optimeDate": primitive.NewDateTimeFromTime(now.Add(-3 * time.Second))},
+			bson.M{"name": "rs2:27017", "stateStr": "ARBITER"},
+		},
+		"optimes": bson.M{
+			"lastAppliedWallTime":   primitive.NewDateTimeFromTime(now),
+			"lastCommittedWallTime": primitive.NewDateTimeFromTime(now.Add(-500 * time.Millisecond)),
+		},
+	}
+
+	sample := parseReplicationLag(status)
+
+	assert.Equal(t, "rs0:27017", sample.primaryMember)
+	assert.Len(t, sample.memberLags, 2)
+	assert.Equal(t, 3*time.Second, sample.maxLag())
+	assert.Equal(t, 500*time.Millisecond, sample.majorityLag)
+}
+
+// TestReplicationLagFailsRun verifies that the fail action stops the running test and fails it and the following ones
+func TestReplicationLagFailsRun(t *testing.T) {
+	t.Chdir(t.TempDir())
+	admin := &scriptedCommandRunner{reply: func(bson.D) bson.M {
+		now := time.Now()
+		return bson.M{"members": bson.A{
+			bson.M{"name": "rs0:27017", "stateStr": "PRIMARY", "optimeDate": primitive.NewDateTimeFromTime(now)},
+			bson.M{"name": "rs1:27017", "stateStr": "SECONDARY", "optimeDate": primitive.NewDateTimeFromTime(now.Add(-3 * time.Second))},
+		}}
+	}}
+	config := TestingConfig{Threads: 2, Gate: NewGate(), Stop: StopConditions{MaxDuration: 10 * time.Second}}
+	monitor := NewReplicationLagMonitor(admin, 50*time.Millisecond, time.Second, ReplicationLagFail, config.Gate)
+	monitor.Start("replication_lag.csv")
+
+	result, err := Runner{}.Run(NewMemoryCollection(0, 0), "insert", config)
+
+	assert.ErrorContains(t, err, "replication lag of 3s exceeds the threshold of 1s")
+	assert.Equal(t, "replication lag of 3s exceeds the threshold of 1s", result.StopReason)
+	assert.Less(t, result.Duration, 5*time.Second)
+	assert.FileExists(t, "benchmark_results_insert.csv")
+	assert.NoError(t, monitor.Stop())
+	assert.FileExists(t, "replication_lag.csv")
+
+	_, err = Runner{}.Run(NewMemoryCollection(0, 0), "update", config)
+	assert.ErrorContains(t, err, "replication lag of 3s")
+}
+
+// TestChangeStreamWatchers verifies that the events of all watchers are counted and their lag is measured from the
+// writtenAt field
+func TestChangeStreamWatchers(t *testing.T) {
+	watchers := newChangeStreamWatchers(TestingConfig{Watchers: 2})
+	writtenAt := time.Now().Add(-50 * time.Millisecond).UnixNano()
+
+	insert, _ := bson.Marshal(bson.M{"operationType": "insert", "fullDocument": bson.M{"rnd": 1, "writtenAt": writtenAt}})
+	update, _ := bson.Marshal(bson.M{"operationType": "update", "updateDescription": bson.M{"updatedFields": bson.M{"writtenAt": writtenAt}}})
+	unrelated, _ := bson.Marshal(bson.M{"operationType": "delete", "documentKey": bson.M{"_id": 1}})
+
+	watchers.received(0, insert)
+	watchers.received(1, update)
+	watchers.received(1, unrelated)
+
+	assert.Equal(t, []string{"events", "event_lag_mean_ms", "event_lag_p99_ms", "watcher_0_events_per_sec", "watcher_1_events_per_sec"}, watchers.Columns())
+	values := watchers.Values()
+	assert.Equal(t, "3", values[0])
+	lag, err := strconv.ParseFloat(values[1], 64)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, lag, 50.0)
+	assert.Nil(t, newChangeStreamWatchers(TestingConfig{}))
+}
+
+// scriptedCommandRunner records the commands it runs and answers them with the reply function
+type scriptedCommandRunner struct {
+	commands []bson.D
+	reply    func(command bson.D) bson.M
+}
+
+func (f *scriptedCommandRunner) RunCommand(_ context.Context, runCommand interface{}, _ ...*options.RunCmdOptions) *mongo.SingleResult {
+	command := runCommand.(bson.D)
+	f.commands = append(f.commands, command)
+	return mongo.NewSingleResultFromDocument(f.reply(command), nil, nil)
+}
+
+func (f *scriptedCommandRunner) names() []string {
+	var names []string
+	for _, command := range f.commands {
+		names = append(names, command[0].Key)
+	}
+	return names
+}
+
+// TestShardingSetup verifies the commands that shard the collection and pre-split and distribute its chunks
+func TestShardingSetup(t *testing.T) {
+	key, err := ParseShardKey("rnd:hashed")
+	assert.NoError(t, err)
+	_, err = ParseShardKey("rnd:-1")
+	assert.Error(t, err)
+
+	admin := &scriptedCommandRunner{reply: func(command bson.D) bson.M {
+		if command[0].Key == "listShards" {
+			return bson.M{"shards": bson.A{bson.M{"_id": "shard0"}, bson.M{"_id": "shard1"}}}
+		}
+		return bson.M{"ok": 1}
+	}}
+	config := &scriptedCommandRunner{reply: func(bson.D) bson.M {
+		return bson.M{"cursor": bson.M{"firstBatch": bson.A{}}}
+	}}
+
+	setup, err := NewShardingSetup(admin, config, "benchmarking.testdata", key, 4)
+	assert.NoError(t, err)
+	assert.NoError(t, setup.shard())
+
+	assert.Equal(t, []string{"enableSharding", "shardCollection", "split", "split", "split", "listShards", "moveChunk", "moveChunk", "moveChunk", "moveChunk"}, admin.names())
+	middle := admin.commands[3][1].Value.(bson.D)[0].Value.(int64)
+	assert.InDelta(t, 0, float64(middle), 1e3)
+	assert.Equal(t, "shard1", admin.commands[7][2].Value)
+
+	// The split points of a ranged rnd key spread the inserted documents across all chunks
+	setup, err = NewShardingSetup(admin, config, "benchmarking.testdata", bson.D{{Key: "rnd", Value: 1}}, 4)
+	assert.NoError(t, err)
+	points := setup.splitPoints()
+	chunks := make([]int, len(points)+1)
+	random := NewRandomizer()
+	for i := 0; i < 1000; i++ {
+		rnd := newDocument(0, random, TestingConfig{}, nil)["rnd"].(int64)
+		chunks[sort.Search(len(points), func(j int) bool { return rnd < points[j] })]++
+	}
+	for _, count := range chunks {
+		assert.InDelta(t, 250, count, 100)
+	}
+	_, err = NewShardingSetup(admin, config, "benchmarking.testdata", bson.D{{Key: "threadRunCount", Value: 1}}, 4)
+	assert.Error(t, err)
+	_, err = NewShardingSetup(admin, config, "benchmarking.testdata", bson.D{{Key: "threadRunCount", Value: 1}}, 0)
+	assert.NoError(t, err)
+}
+
+// TestShardStatsSampler verifies the per-shard operation rates, document and chunk counts, and balancer activity
+func TestShardStatsSampler(t *testing.T) {
+	t.Chdir(t.TempDir())
+	var calls int64
+	db := &scriptedCommandRunner{reply: func(bson.D) bson.M {
+		calls++
+		return bson.M{"cursor": bson.M{"firstBatch": bson.A{
+			bson.M{"shard": "shard0", "latencyStats": bson.M{"writes": bson.M{"ops": calls * 30}}, "count": calls * 30},
+			bson.M{"shard": "shard1", "latencyStats": bson.M{"writes": bson.M{"ops": calls * 10}}, "count": calls * 10},
+		}}}
+	}}
+	admin := &scriptedCommandRunner{reply: func(bson.D) bson.M {
+		return bson.M{"inBalancerRound": true, "numBalancerRounds": int64(5)}
+	}}
+	config := &scriptedCommandRunner{reply: func(command bson.D) bson.M {
+		switch command[0].Value {
+		case "collections":
+			return bson.M{"cursor": bson.M{"firstBatch": bson.A{bson.M{"_id": "benchmarking.testdata", "uuid": "id"}}}}
+		case "chunks":
+			return bson.M{"cursor": bson.M{"firstBatch": bson.A{bson.M{"_id": "shard0", "chunks": int32(3)}, bson.M{"_id": "shard1", "chunks": int32(2)}}}}
+		}
+		return bson.M{"n": int32(1)}
+	}}
+
+	sampler := NewShardStatsSampler(db, admin, config, "benchmarking", "testdata", time.Second)
+	sampler.sample()
+	sampler.previousTime = sampler.previousTime.Add(-time.Second)
+	sampler.sample()
+	assert.NoError(t, sampler.writeCSV("shard_stats.csv"))
+
+	file, err := os.Open("shard_stats.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"t", "balancer_in_round", "balancer_rounds", "migrations",
+		"ops_per_sec_shard0", "ops_share_shard0", "docs_shard0", "chunks_shard0",
+		"ops_per_sec_shard1", "ops_share_shard1", "docs_shard1", "chunks_shard1"}, records[0])
+	assert.Equal(t, []string{"true", "5", "1", "", "", "30", "3"}, records[1][1:8])
+	assert.Equal(t, "0.7500", records[2][5])
+	assert.Equal(t, "2", records[2][11])
+}
+
+// TestGatePausesWorkers verifies that workers wait while the gate is closed, unless the test stops
+func TestGatePausesWorkers(t *testing.T) {
+	gate := NewGate()
+	gate.set(true)
+
+	released := make(chan struct{})
+	go func() {
+		assert.True(t, gate.Wait(nil))
+		close(released)
+	}()
+
+	select {
+	case <-released:
+		t.Fatal("worker passed a closed gate")
+	case <-time.After(20 * time.Millisecond):
+	}
+	gate.set(false)
+	<-released
+
+	// A stopped test releases the workers waiting at a closed gate
+	t.Chdir(t.TempDir())
+	gate.set(true)
+	config := TestingConfig{Threads: 2, Gate: gate, Stop: StopConditions{MaxDuration: 50 * time.Millisecond}}
+	result, err := Runner{}.Run(NewMemoryCollection(0, 0), "insert", config)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), result.Count)
+	assert.Equal(t, "duration of 50ms reached", result.StopReason)
+
+	var nilGate *Gate
+	assert.True(t, nilGate.Wait(nil))
+}
+
+// TestDriverMetrics verifies that command and pool events are reported per interval
+func TestDriverMetrics(t *testing.T) {
+	driverMetrics := NewDriverMetrics()
+	commandMonitor := driverMetrics.CommandMonitor()
+	poolMonitor := driverMetrics.PoolMonitor()
+
+	commandMonitor.Succeeded(context.Background(), &event.CommandSucceededEvent{CommandFinishedEvent: event.CommandFinishedEvent{Duration: 2 * time.Millisecond}})
+	commandMonitor.Failed(context.Background(), &event.CommandFailedEvent{CommandFinishedEvent: event.CommandFinishedEvent{Duration: 4 * time.Millisecond}})
+	poolMonitor.Event(&event.PoolEvent{Type: event.ConnectionCreated})
+	poolMonitor.Event(&event.PoolEvent{Type: event.GetSucceeded, Duration: time.Millisecond})
+	poolMonitor.Event(&event.PoolEvent{Type: event.PoolCleared})
+
+	values := driverMetrics.Values()
+	assert.Len(t, values, len(driverMetrics.Columns()))
+	assert.Equal(t, []string{"2", "1", "3.000", "4.000", "1.000", "1.000", "0", "1", "0", "1"}, values)
+
+	values = driverMetrics.Values()
+	assert.Equal(t, "0.000", values[2])
+	assert.Equal(t, "2", values[0])
+}
@@ -0,0 +1,53 @@
+package mongobench
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// logger receives the progress of the tests; nothing is logged unless SetLogger is called
+var logger = log.New(io.Discard, "", 0)
+
+// SetLogger sets the logger the package reports the progress of the tests to, e.g. log.Default()
+func SetLogger(l *log.Logger) {
+	logger = l
+}
+
+// Output decides where the tests save their CSV files. The zero value saves them to the working directory.
+type Output struct {
+	Dir      string // directory of the files, the working directory if empty
+	Disabled bool   // save no files
+}
+
+// save writes the records to the named file of the output directory, unless saving is disabled
+func (o Output) save(description, name string, records [][]string) error {
+	if o.Disabled {
+		return nil
+	}
+	filename := filepath.Join(o.Dir, name)
+	if err := writeCSVFile(filename, records); err != nil {
+		return err
+	}
+	logger.Printf("%s saved to %s", description, filename)
+	return nil
+}
+
+func writeCSVFile(filename string, records [][]string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create CSV file: %v", err)
+	}
+	writer := csv.NewWriter(file)
+	if err := writer.WriteAll(records); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write records to %s: %v", filename, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %v", filename, err)
+	}
+	return nil
+}
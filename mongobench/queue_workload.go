@@ -0,0 +1,192 @@
+package mongobench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const queueInsertBatch = 1000
+
+func init() {
+	RegisterWorkload(WorkloadDefinition{Name: "queue", Fresh: true, New: func(env WorkloadEnv) (Workload, error) {
+		// Every item is claimed once, so more items than operations are never needed
+		items := env.Config.QueueItems
+		if limit := int(env.Config.Stop.MaxOps); limit > 0 && (items == 0 || limit < items) {
+			items = limit
+		}
+		return newQueueWorkload(env.Config, items, env.RetryStats), nil
+	}})
+}
+
+// queueWorkload uses the collection as a work queue: the items are claimed with findOneAndUpdate in the order they
+// were enqueued, processed for the think time and marked done. Every claim is tracked to detect items that were
+// claimed twice.
+type queueWorkload struct {
+	items      int
+	thinkTime  time.Duration
+	retry      RetryPolicy
+	retryStats *RetryStats
+
+	claimedBy      sync.Map
+	claims         atomic.Int64
+	duplicates     atomic.Int64
+	lostCompletion atomic.Int64
+	empty          atomic.Int64
+	claimLatency   metrics.Histogram
+	doneLatency    metrics.Histogram
+
+	mu             sync.Mutex
+	previousClaims int64
+	previousTime   time.Time
+}
+
+func newQueueWorkload(config TestingConfig, items int, retryStats *RetryStats) *queueWorkload {
+	return &queueWorkload{
+		items:        items,
+		thinkTime:    config.ThinkTime,
+		retry:        config.Retry,
+		retryStats:   retryStats,
+		claimLatency: metrics.NewHistogram(metrics.NewUniformSample(100000)),
+		doneLatency:  metrics.NewHistogram(metrics.NewUniformSample(100000)),
+		previousTime: time.Now(),
+	}
+}
+
+// Setup enqueues the pending items and creates the index the claims use
+func (q *queueWorkload) Setup(collection CollectionAPI) error {
+	// The in-memory collection cannot claim items, so the test fails before enqueuing them
+	if _, ok := collection.(*MemoryCollection); ok {
+		return errMemoryUnsupported("queue test")
+	}
+	index := mongo.IndexModel{Keys: bson.D{{Key: "status", Value: 1}, {Key: "seq", Value: 1}}}
+	if _, err := collection.CreateIndexes(context.Background(), []mongo.IndexModel{index}); err != nil {
+		return fmt.Errorf("failed to create the queue index: %v", err)
+	}
+
+	now := time.Now()
+	for offset := 0; offset < q.items; offset += queueInsertBatch {
+		var batch []interface{}
+		for seq := offset; seq < offset+queueInsertBatch && seq < q.items; seq++ {
+			batch = append(batch, bson.M{"seq": seq, "status": "pending", "claims": 0, "createdAt": now})
+		}
+		if _, err := collection.InsertMany(context.Background(), batch); err != nil {
+			return fmt.Errorf("failed to enqueue items: %v", err)
+		}
+	}
+	logger.Printf("Enqueued %d items", q.items)
+	q.previousTime = time.Now()
+	return nil
+}
+
+// Run claims the oldest pending item, processes it for the think time and marks it done. Claims are retried by the
+// queue itself, so a failed completion does not claim another item.
+func (q *queueWorkload) Run(collection CollectionAPI, w *Worker) error {
+	worker := w.ID
+	filter := bson.M{"status": "pending"}
+	claim := bson.M{
+		"$set": bson.M{"status": "processing", "claimedBy": worker, "claimedAt": time.Now()},
+		"$inc": bson.M{"claims": 1},
+	}
+	opts := options.FindOneAndUpdate().SetSort(bson.M{"seq": 1}).SetReturnDocument(options.After)
+
+	var item struct {
+		ID     interface{} `bson:"_id"`
+		Claims int         `bson:"claims"`
+	}
+	start := time.Now()
+	err := q.retry.run(q.retryStats, func() error {
+		return collection.FindOneAndUpdate(context.Background(), filter, claim, opts).Decode(&item)
+	})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		q.empty.Add(1)
+		return ErrStopWorker
+	}
+	if err != nil {
+		return err
+	}
+	q.claimLatency.Update(int64(time.Since(start)))
+	q.claims.Add(1)
+	if _, claimed := q.claimedBy.LoadOrStore(item.ID, worker); claimed || item.Claims > 1 {
+		q.duplicates.Add(1)
+		logger.Printf("Item %v was claimed more than once", item.ID)
+	}
+
+	if q.thinkTime > 0 {
+		time.Sleep(q.thinkTime)
+	}
+
+	done := bson.M{"$set": bson.M{"status": "done", "doneAt": time.Now()}}
+	var result *mongo.UpdateResult
+	start = time.Now()
+	err = q.retry.run(q.retryStats, func() error {
+		var err error
+		result, err = collection.UpdateOne(context.Background(), bson.M{"_id": item.ID, "status": "processing", "claimedBy": worker}, done)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	q.doneLatency.Update(int64(time.Since(start)))
+	if result.MatchedCount == 0 {
+		q.lostCompletion.Add(1)
+		logger.Printf("Item %v was taken over by another worker before it was done", item.ID)
+	}
+	return nil
+}
+
+// Teardown logs the items that were claimed more than once or were left unfinished according to the server
+func (q *queueWorkload) Teardown(collection CollectionAPI) error {
+	reclaimed, err := collection.CountDocuments(context.Background(), bson.M{"claims": bson.M{"$gt": 1}})
+	if err != nil {
+		return fmt.Errorf("failed to verify the queue: %v", err)
+	}
+	unfinished, err := collection.CountDocuments(context.Background(), bson.M{"status": bson.M{"$ne": "done"}})
+	if err != nil {
+		return fmt.Errorf("failed to verify the queue: %v", err)
+	}
+	logger.Printf("Queue: %d claims, %d duplicate claims seen by workers, %d items claimed more than once, %d items not done",
+		q.claims.Load(), q.duplicates.Load(), reclaimed, unfinished)
+	return nil
+}
+
+// Columns implements MetricsSource
+func (q *queueWorkload) Columns() []string {
+	return []string{"claims", "claims_per_sec", "claim_mean_ms", "claim_p99_ms", "done_mean_ms", "duplicate_claims", "lost_completions", "queue_empty"}
+}
+
+// Values implements MetricsSource; the rate and latencies cover the interval since the previous call
+func (q *queueWorkload) Values() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	claims := q.claims.Load()
+	rate := float64(claims-q.previousClaims) / now.Sub(q.previousTime).Seconds()
+	q.previousClaims = claims
+	q.previousTime = now
+
+	claim := q.claimLatency.Snapshot()
+	q.claimLatency.Clear()
+	done := q.doneLatency.Snapshot()
+	q.doneLatency.Clear()
+
+	return []string{
+		fmt.Sprintf("%d", claims),
+		fmt.Sprintf("%.2f", rate),
+		formatMillis(claim.Mean()),
+		formatMillis(claim.Percentile(0.99)),
+		formatMillis(done.Mean()),
+		fmt.Sprintf("%d", q.duplicates.Load()),
+		fmt.Sprintf("%d", q.lostCompletion.Load()),
+		fmt.Sprintf("%d", q.empty.Load()),
+	}
+}
@@ -0,0 +1,74 @@
+package mongobench
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+type Randomizer struct {
+	rnd *rand.Rand
+}
+
+// NewRandomizer initializes a new Randomizer instance with a seeded random number generator.
+func NewRandomizer() *Randomizer {
+	src := rand.NewSource(time.Now().UnixNano())
+	return &Randomizer{
+		rnd: rand.New(src),
+	}
+}
+
+// RandomInt63 returns a non-negative pseudo-random 63-bit integer as an int64
+func (r *Randomizer) RandomInt63() int64 {
+	return r.rnd.Int63()
+}
+
+// RandomIntn returns a non-negative pseudo-random int in [0,n)
+func (r *Randomizer) RandomIntn(n int) int {
+	return r.rnd.Intn(n)
+}
+
+// RandomInt63n returns a non-negative pseudo-random int64 in [0,n)
+func (r *Randomizer) RandomInt63n(n int64) int64 {
+	return r.rnd.Int63n(n)
+}
+
+// RandomUint64 returns a pseudo-random 64-bit integer as a uint64
+func (r *Randomizer) RandomUint64() uint64 {
+	return r.rnd.Uint64()
+}
+
+// RandomUint64n returns a pseudo-random uint64 in [0,n)
+func (r *Randomizer) RandomUint64n(n uint64) uint64 {
+	if n <= math.MaxInt64 {
+		return uint64(r.rnd.Int63n(int64(n)))
+	}
+	for {
+		if value := r.rnd.Uint64(); value < n {
+			return value
+		}
+	}
+}
+
+// RandomFloat64 returns a pseudo-random float64 in [0.0,1.0)
+func (r *Randomizer) RandomFloat64() float64 {
+	return r.rnd.Float64()
+}
+
+// RandomBytes returns n pseudo-random bytes
+func (r *Randomizer) RandomBytes(n int) []byte {
+	b := make([]byte, n)
+	r.rnd.Read(b)
+	return b
+}
+
+const alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// RandomString returns a pseudo-random alphanumeric string of length n
+func (r *Randomizer) RandomString(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = alphanumeric[r.rnd.Intn(len(alphanumeric))]
+	}
+	return string(b)
+}
@@ -0,0 +1,182 @@
+package mongobench
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReplicationLagAction determines what happens when the replication lag exceeds the threshold
+type ReplicationLagAction string
+
+const (
+	ReplicationLagPause ReplicationLagAction = "pause"
+	ReplicationLagFail  ReplicationLagAction = "fail"
+)
+
+type replicationLagSample struct {
+	timestamp     int64
+	majorityLag   time.Duration
+	memberLags    map[string]time.Duration
+	primaryMember string
+}
+
+// ReplicationLagMonitor samples replSetGetStatus and records how far every member and the majority commit point
+// fall behind the primary. If a threshold is set, the workers are paused or the run is failed while it is exceeded.
+type ReplicationLagMonitor struct {
+	admin     CommandRunner
+	interval  time.Duration
+	threshold time.Duration
+	action    ReplicationLagAction
+	gate      *Gate
+	loop      sampleLoop
+	filename  string
+
+	samples []replicationLagSample
+}
+
+// NewReplicationLagMonitor creates a monitor running replSetGetStatus against the given admin database. The gate is
+// closed while the lag exceeds the threshold and the action is ReplicationLagPause, and fails once the threshold is
+// exceeded with ReplicationLagFail, which stops the running test; a zero threshold disables both.
+func NewReplicationLagMonitor(admin CommandRunner, interval, threshold time.Duration, action ReplicationLagAction, gate *Gate) *ReplicationLagMonitor {
+	return &ReplicationLagMonitor{
+		admin:     admin,
+		interval:  interval,
+		threshold: threshold,
+		action:    action,
+		gate:      gate,
+	}
+}
+
+// Start begins sampling at the configured interval; the time series is written to filename when the monitor stops
+func (m *ReplicationLagMonitor) Start(filename string) {
+	m.filename = filename
+	m.loop.start(m.interval, m.sample)
+}
+
+// Stop ends sampling, reopens the gate and writes the collected time series
+func (m *ReplicationLagMonitor) Stop() error {
+	m.loop.stop()
+	if m.gate != nil {
+		m.gate.set(false)
+	}
+	return m.writeCSV()
+}
+
+func (m *ReplicationLagMonitor) sample() {
+	var status bson.M
+	if err := m.admin.RunCommand(context.Background(), bson.D{{Key: "replSetGetStatus", Value: 1}}).Decode(&status); err != nil {
+		logger.Printf("Failed to sample replSetGetStatus: %v", err)
+		return
+	}
+
+	sample := parseReplicationLag(status)
+	sample.timestamp = time.Now().Unix()
+	m.samples = append(m.samples, sample)
+
+	if m.threshold <= 0 {
+		return
+	}
+	lag := sample.maxLag()
+	switch {
+	case lag > m.threshold && m.action == ReplicationLagFail && m.gate != nil:
+		m.gate.fail(fmt.Errorf("replication lag of %v exceeds the threshold of %v", lag, m.threshold))
+	case lag > m.threshold && m.gate != nil:
+		logger.Printf("Replication lag of %v exceeds the threshold of %v, pausing workers", lag, m.threshold)
+		m.gate.set(true)
+	case m.gate != nil:
+		m.gate.set(false)
+	}
+}
+
+// parseReplicationLag computes the lag of each member's optime and of the majority commit point behind the primary
+func parseReplicationLag(status bson.M) replicationLagSample {
+	sample := replicationLagSample{memberLags: make(map[string]time.Duration)}
+
+	members, _ := status["members"].(bson.A)
+	optimes := make(map[string]time.Time)
+	var primaryOptime time.Time
+	for _, member := range members {
+		doc, ok := member.(bson.M)
+		if !ok {
+			continue
+		}
+		optime, ok := doc["optimeDate"].(primitive.DateTime)
+		if !ok {
+			continue // arbiters have no optime
+		}
+		name, _ := doc["name"].(string)
+		optimes[name] = optime.Time()
+		if doc["stateStr"] == "PRIMARY" {
+			primaryOptime = optime.Time()
+			sample.primaryMember = name
+		}
+	}
+	if sample.primaryMember == "" {
+		return sample
+	}
+
+	for name, optime := range optimes {
+		sample.memberLags[name] = primaryOptime.Sub(optime)
+	}
+	if status, ok := status["optimes"].(bson.M); ok {
+		applied, appliedOk := status["lastAppliedWallTime"].(primitive.DateTime)
+		committed, committedOk := status["lastCommittedWallTime"].(primitive.DateTime)
+		if appliedOk && committedOk {
+			sample.majorityLag = applied.Time().Sub(committed.Time())
+		}
+	}
+	return sample
+}
+
+func (s replicationLagSample) maxLag() time.Duration {
+	var lag time.Duration
+	for _, memberLag := range s.memberLags {
+		if memberLag > lag {
+			lag = memberLag
+		}
+	}
+	return lag
+}
+
+func (m *ReplicationLagMonitor) writeCSV() error {
+	memberSet := make(map[string]bool)
+	for _, sample := range m.samples {
+		for name := range sample.memberLags {
+			memberSet[name] = true
+		}
+	}
+	var members []string
+	for name := range memberSet {
+		members = append(members, name)
+	}
+	sort.Strings(members)
+
+	header := []string{"t", "primary", "majority_commit_lag_ms", "max_lag_ms"}
+	for _, name := range members {
+		header = append(header, "lag_ms_"+name)
+	}
+	records := [][]string{header}
+	for _, sample := range m.samples {
+		record := []string{
+			fmt.Sprintf("%d", sample.timestamp),
+			sample.primaryMember,
+			fmt.Sprintf("%d", sample.majorityLag.Milliseconds()),
+			fmt.Sprintf("%d", sample.maxLag().Milliseconds()),
+		}
+		for _, name := range members {
+			if lag, ok := sample.memberLags[name]; ok {
+				record = append(record, fmt.Sprintf("%d", lag.Milliseconds()))
+			} else {
+				record = append(record, "")
+			}
+		}
+		records = append(records, record)
+	}
+
+	return Output{}.save("Replication lag", m.filename, records)
+}
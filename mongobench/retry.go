@@ -0,0 +1,181 @@
+package mongobench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RetryPolicy configures application-level retries on top of the driver's retryable reads and writes
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts per operation, including the first one
+	Backoff     time.Duration // wait before the first retry, doubled for every further retry
+	MaxBackoff  time.Duration // upper bound for the wait between two attempts
+}
+
+// RetryStats counts operations that needed retries and the latency the retries added
+type RetryStats struct {
+	retried      atomic.Int64
+	exhausted    atomic.Int64
+	retryLatency atomic.Int64
+}
+
+// run executes op until it succeeds or the policy's attempts are used up. Duplicate key errors and missing documents
+// are never retried, as another attempt cannot succeed.
+func (p RetryPolicy) run(stats *RetryStats, op func() error) error {
+	err := op()
+	if err == nil || p.MaxAttempts <= 1 || isPermanent(err) {
+		return err
+	}
+
+	firstFailure := time.Now()
+	backoff := p.Backoff
+	for attempt := 2; attempt <= p.MaxAttempts; attempt++ {
+		time.Sleep(backoff)
+		if backoff *= 2; p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+
+		if err = op(); err == nil || isPermanent(err) {
+			break
+		}
+	}
+
+	stats.retryLatency.Add(int64(time.Since(firstFailure)))
+	if err == nil {
+		stats.retried.Add(1)
+	} else {
+		stats.exhausted.Add(1)
+	}
+	return err
+}
+
+func isPermanent(err error) bool {
+	return mongo.IsDuplicateKeyError(err) || errors.Is(err, mongo.ErrNoDocuments)
+}
+
+// Columns implements MetricsSource
+func (s *RetryStats) Columns() []string {
+	return []string{"retried", "retries_exhausted", "retry_latency_ms"}
+}
+
+// Values implements MetricsSource
+func (s *RetryStats) Values() []string {
+	return []string{
+		fmt.Sprintf("%d", s.retried.Load()),
+		fmt.Sprintf("%d", s.exhausted.Load()),
+		formatMillis(float64(s.retryLatency.Load())),
+	}
+}
+
+// retryableReads are the read commands the driver retries with retryable reads
+var retryableReads = map[string]bool{
+	"find": true, "aggregate": true, "distinct": true, "count": true,
+	"listCollections": true, "listIndexes": true, "listDatabases": true,
+}
+
+// DriverRetries counts the retries of the driver's retryable writes and reads as seen by a command monitor. A write is
+// retried with the session and transaction number of the first attempt; a read is counted when the same read command
+// is sent again on the session after it failed. Sessions are reused, so an application-level retry of a read may be
+// counted as well. The counts cover the running test.
+type DriverRetries struct {
+	mu       sync.Mutex
+	sessions map[string]*sessionCommand // the last command of every session, by session ID
+	started  map[int64]string           // the session of every command in flight, by request ID
+
+	writes atomic.Int64
+	reads  atomic.Int64
+}
+
+// sessionCommand is the last command sent on a session
+type sessionCommand struct {
+	name      string
+	txnNumber int64
+	write     bool // whether the command is a retryable write, with a transaction number
+	failed    bool
+}
+
+func NewDriverRetries() *DriverRetries {
+	return &DriverRetries{sessions: make(map[string]*sessionCommand), started: make(map[int64]string)}
+}
+
+// CommandMonitor returns the monitor to register with the client options
+func (d *DriverRetries) CommandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, e *event.CommandStartedEvent) {
+			d.commandStarted(e.RequestID, e.CommandName, e.Command)
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			d.commandFinished(e.RequestID, false)
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			d.commandFinished(e.RequestID, true)
+		},
+	}
+}
+
+func (d *DriverRetries) commandStarted(requestID int64, name string, command bson.Raw) {
+	lsid, err := command.LookupErr("lsid", "id")
+	if err != nil {
+		return
+	}
+	session := string(lsid.Value)
+	txnNumber, write := command.Lookup("txnNumber").Int64OK()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, err := command.LookupErr("autocommit"); err == nil {
+		// Commands of a transaction share its transaction number
+		delete(d.sessions, session)
+		return
+	}
+	previous := d.sessions[session]
+	switch {
+	case previous == nil:
+	case write && previous.write && previous.txnNumber == txnNumber:
+		d.writes.Add(1)
+	case !write && retryableReads[name] && !previous.write && previous.failed && previous.name == name:
+		d.reads.Add(1)
+	}
+	d.sessions[session] = &sessionCommand{name: name, txnNumber: txnNumber, write: write}
+	d.started[requestID] = session
+}
+
+func (d *DriverRetries) commandFinished(requestID int64, failed bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	session, ok := d.started[requestID]
+	if !ok {
+		return
+	}
+	delete(d.started, requestID)
+	if command := d.sessions[session]; command != nil && failed {
+		command.failed = true
+	}
+}
+
+// begin resets the counts when a test starts; a nil DriverRetries counts nothing
+func (d *DriverRetries) begin() {
+	if d == nil {
+		return
+	}
+	d.writes.Store(0)
+	d.reads.Store(0)
+}
+
+// Columns implements MetricsSource
+func (d *DriverRetries) Columns() []string {
+	return []string{"driver_write_retries", "driver_read_retries"}
+}
+
+// Values implements MetricsSource
+func (d *DriverRetries) Values() []string {
+	return []string{fmt.Sprintf("%d", d.writes.Load()), fmt.Sprintf("%d", d.reads.Load())}
+}
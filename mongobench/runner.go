@@ -0,0 +1,309 @@
+// Package mongobench runs MongoDB benchmarks. A Runner runs a workload, selected by its test type, against a
+// collection until one of the stop conditions of the TestingConfig is reached, and returns the result of the test.
+package mongobench
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StopConditions end a test as soon as the first of the configured limits is reached. Zero values disable a limit.
+type StopConditions struct {
+	MaxOps      int64         // operations started by all workers
+	MaxDuration time.Duration // wall time of the test
+	MaxErrors   int64         // failed operations
+	LatencySLO  time.Duration // p99 latency an interval may not exceed
+	SLOWindow   int           // consecutive intervals above the SLO that end the test
+}
+
+func (c StopConditions) enabled() bool {
+	return c.MaxOps > 0 || c.MaxDuration > 0 || c.MaxErrors > 0 || c.LatencySLO > 0
+}
+
+// stopper tracks the stop conditions of a running test; workers ask it before every operation. The operation limit
+// is split evenly across the workers, so every worker runs its share even if the others are faster.
+type stopper struct {
+	conditions StopConditions
+	quotas     []int64
+	ops        atomic.Int64
+	errors     atomic.Int64
+	breaches   int
+	timer      *time.Timer
+
+	once   sync.Once
+	done   chan struct{}
+	reason string
+}
+
+func newStopper(conditions StopConditions, workers int) *stopper {
+	s := &stopper{conditions: conditions, done: make(chan struct{})}
+	if conditions.MaxOps > 0 {
+		s.quotas = make([]int64, workers)
+		for i := range s.quotas {
+			s.quotas[i] = conditions.MaxOps / int64(workers)
+			if int64(i) < conditions.MaxOps%int64(workers) {
+				s.quotas[i]++
+			}
+		}
+	}
+	return s
+}
+
+// start arms the duration limit; call it when the workload starts
+func (s *stopper) start() {
+	if s.conditions.MaxDuration > 0 {
+		s.timer = time.AfterFunc(s.conditions.MaxDuration, func() {
+			s.stop(fmt.Sprintf("duration of %v reached", s.conditions.MaxDuration))
+		})
+	}
+}
+
+// next reserves the next operation of a worker and reports false once the worker is to stop
+func (s *stopper) next(worker int) bool {
+	select {
+	case <-s.done:
+		return false
+	default:
+	}
+	if s.quotas != nil {
+		if s.quotas[worker] == 0 {
+			return false
+		}
+		s.quotas[worker]--
+	}
+	s.ops.Add(1)
+	return true
+}
+
+// refund gives back the operation a worker reserved without running it, so it does not count against the limit
+func (s *stopper) refund(worker int) {
+	if s.quotas != nil {
+		s.quotas[worker]++
+	}
+	s.ops.Add(-1)
+}
+
+// failed counts a failed operation against the error budget
+func (s *stopper) failed() {
+	if errors := s.errors.Add(1); s.conditions.MaxErrors > 0 && errors >= s.conditions.MaxErrors {
+		s.stop(fmt.Sprintf("error budget of %d failed operations exhausted", s.conditions.MaxErrors))
+	}
+}
+
+// observeInterval checks the p99 latency of the last interval against the latency SLO
+func (s *stopper) observeInterval(p99 time.Duration) {
+	if s.conditions.LatencySLO <= 0 {
+		return
+	}
+	if p99 <= s.conditions.LatencySLO {
+		s.breaches = 0
+		return
+	}
+	s.breaches++
+	if window := max(s.conditions.SLOWindow, 1); s.breaches >= window {
+		s.stop(fmt.Sprintf("p99 latency above the SLO of %v for %d seconds", s.conditions.LatencySLO, window))
+	}
+}
+
+func (s *stopper) stop(reason string) {
+	s.once.Do(func() {
+		s.reason = reason
+		close(s.done)
+	})
+}
+
+// finish stops the test if no condition did and returns the reason it stopped
+func (s *stopper) finish() string {
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	if s.conditions.MaxOps > 0 && s.ops.Load() >= s.conditions.MaxOps {
+		s.stop(fmt.Sprintf("%d operations reached", s.conditions.MaxOps))
+	}
+	s.stop("all workers finished")
+	return s.reason
+}
+
+// Runner runs every test type on the same code path and stops it on whichever of config.Stop is reached first
+type Runner struct{}
+
+// Run runs a single test and returns its result
+func (t Runner) Run(collection CollectionAPI, testType string, config TestingConfig) (TestResult, error) {
+	return t.run(collection, testType, config, fetchDocumentIDs)
+}
+
+// RunSequence runs the insert, update, delete, and upsert tests one after the other
+func (t Runner) RunSequence(collection CollectionAPI, config TestingConfig) ([]TestResult, error) {
+	var results []TestResult
+	for _, test := range []string{"insert", "update", "delete", "upsert"} {
+		result, err := t.Run(collection, test, config)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (t Runner) run(collection CollectionAPI, testType string, config TestingConfig, fetchDocIDs func(CollectionAPI, int64, string) ([]primitive.ObjectID, error)) (TestResult, error) {
+	definition, err := lookupWorkload(testType)
+	if err != nil {
+		return TestResult{}, err
+	}
+	if !config.Stop.enabled() {
+		return TestResult{}, fmt.Errorf("no stop condition, set the number of operations, a duration, an error budget or a latency SLO")
+	}
+	if err := config.Gate.Err(); err != nil {
+		return TestResult{}, err
+	}
+	logger.Printf("Starting %s test...\n", testType)
+	// Faults are injected into the operations of the workers only
+	setup := undecorated(collection)
+	if err := prepareCollection(setup, config, definition.Fresh); err != nil {
+		return TestResult{}, err
+	}
+
+	threads := config.Threads
+	random := NewRandomizer()
+	var data = make([]byte, 1024*2)
+	for i := 0; i < len(data); i++ {
+		data[i] = byte(random.RandomIntn(256))
+	}
+
+	retryStats := &RetryStats{}
+	var writes *WriteLog
+	if config.Verify {
+		writes = newWriteLog(definition.Fresh && config.DropDb)
+	}
+	workload, err := definition.New(WorkloadEnv{Config: config, RetryStats: retryStats, FetchDocIDs: fetchDocIDs, Data: data, Writes: writes})
+	if err != nil {
+		return TestResult{}, err
+	}
+	if err := workload.Setup(setup); err != nil {
+		return TestResult{}, err
+	}
+	var workloadSources []MetricsSource
+	build := newIndexBuild(config)
+	if build != nil {
+		workloadSources = append(workloadSources, build)
+	}
+	workloadSources = append(workloadSources, workload)
+	watchers := newChangeStreamWatchers(config)
+	if watchers != nil {
+		workloadSources = append(workloadSources, watchers)
+		if err := watchers.start(collection); err != nil {
+			err = fmt.Errorf("failed to start change stream watchers: %v", err)
+			if teardownErr := workload.Teardown(setup); teardownErr != nil {
+				err = errors.Join(err, fmt.Errorf("failed to tear down the %s test: %v", testType, teardownErr))
+			}
+			return TestResult{}, err
+		}
+	}
+
+	// Start the ticker just before starting the main workload goroutines
+	stop := newStopper(config.Stop, threads)
+	recorder := newMetricsRecorder([]string{"t", "count", "mean", "m1_rate", "m5_rate", "m15_rate"}, config.metricsSources(retryStats, workloadSources...)...)
+	recorder.intervalObserver = stop
+	config.Failover.begin(threads)
+	config.DriverRetries.begin()
+	if faulty, ok := collection.(*FaultyCollection); ok {
+		faulty.injector.restart()
+	}
+	recorder.start()
+	stop.start()
+	go func() {
+		select {
+		case <-config.Gate.failure():
+			stop.stop(config.Gate.Err().Error())
+		case <-stop.done:
+		}
+	}()
+	if build != nil {
+		recorder.observer = build
+		build.start(collection)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func(threadID int) {
+			defer wg.Done()
+			worker := &Worker{ID: threadID, Random: NewRandomizer(), Stopped: stop.done}
+			collection := collectionForWorker(collection, threadID)
+
+			for stop.next(threadID) {
+				if !config.Gate.Wait(stop.done) {
+					break
+				}
+				start := time.Now()
+				switch err := workload.Run(collection, worker); err {
+				case nil:
+					recorder.mark(start)
+					config.Failover.observe(threadID, true)
+				case ErrStopWorker:
+					return
+				case ErrNoEffect:
+					// Neither a measured operation nor a failure, nor part of the operation limit
+					stop.refund(threadID)
+				default:
+					logger.Printf("%s failed: %v", testType, err)
+					stop.failed()
+					config.Failover.observe(threadID, false)
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	if build != nil {
+		build.stopped()
+	}
+	reason := stop.finish()
+	logger.Printf("The %s test stopped: %s", testType, reason)
+
+	// The test is finished even if tearing it down or saving its results fails, so these errors are collected
+	var errs []error
+	if err := workload.Teardown(setup); err != nil {
+		errs = append(errs, fmt.Errorf("failed to tear down the %s test: %v", testType, err))
+	}
+	if watchers != nil {
+		watchers.stop()
+	}
+	if build != nil {
+		if err := build.finish(); err != nil {
+			errs = append(errs, fmt.Errorf("the index build of the %s test failed: %v", testType, err))
+		}
+	}
+	recorder.stop()
+	if err := recorder.writeCSV(config.Output, config.resultsFilename(testType)); err != nil {
+		errs = append(errs, err)
+	}
+	if build != nil {
+		if err := build.writeReport(config.Output, fmt.Sprintf("index_build_%s.csv", testType)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	result := recorder.result(testType)
+	result.StopReason = reason
+	if err := config.Gate.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("the %s test failed: %v", testType, err))
+	}
+	if result.Failover, err = config.Failover.finish(testType, config); err != nil {
+		errs = append(errs, err)
+	}
+	if writes != nil {
+		verification, err := writes.verify(setup)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to verify the %s test: %v", testType, err))
+		} else {
+			logger.Printf("Verification of the %s test: %s", testType, verification)
+			result.Verification = &verification
+		}
+	}
+	return result, errors.Join(errs...)
+}
@@ -0,0 +1,99 @@
+package mongobench
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	RegisterWorkload(WorkloadDefinition{Name: "scan", New: newQueryTest(func(collection CollectionAPI, config TestingConfig) (queryWorkload, error) {
+		// The range filters are operators the in-memory collection cannot evaluate
+		if _, ok := collection.(*MemoryCollection); ok {
+			return nil, errMemoryUnsupported("scan test")
+		}
+		return newScanWorkload(config)
+	})})
+}
+
+// scanWorkload issues range queries over a numeric field and fully iterates the cursor, covering pagination and
+// export paths
+type scanWorkload struct {
+	field   string
+	min     int64
+	span    uint64
+	width   uint64
+	sort    int
+	project []string
+	limit   int64
+	batch   int32
+	stats   *QueryStats
+}
+
+func newScanWorkload(config TestingConfig) (*scanWorkload, error) {
+	if config.ScanMax <= config.ScanMin {
+		return nil, fmt.Errorf("the scan range maximum %d must be greater than its minimum %d", config.ScanMax, config.ScanMin)
+	}
+	// The span is unsigned, as it does not fit an int64 if the range covers more than half of the int64 values
+	span := uint64(config.ScanMax) - uint64(config.ScanMin)
+	width := uint64(config.ScanWidth)
+	if config.ScanWidth <= 0 {
+		width = max(span/1000, 1)
+	}
+	if width > span {
+		return nil, fmt.Errorf("the scan width %d exceeds the range of %d", width, span)
+	}
+	if config.ScanSort < -1 || config.ScanSort > 1 {
+		return nil, fmt.Errorf("invalid scan sort order %d, expected 1, -1 or 0", config.ScanSort)
+	}
+	return &scanWorkload{
+		field:   config.ScanField,
+		min:     config.ScanMin,
+		span:    span,
+		width:   width,
+		sort:    config.ScanSort,
+		project: config.ScanProjection,
+		limit:   config.ScanLimit,
+		batch:   config.BatchSize,
+		stats:   NewQueryStats(),
+	}, nil
+}
+
+// run scans a random window of the configured width
+func (w *scanWorkload) run(collection CollectionAPI, r *Randomizer) error {
+	lower := int64(uint64(w.min) + r.RandomUint64n(w.span-w.width+1))
+	upper := int64(uint64(lower) + w.width)
+	filter := bson.D{{Key: w.field, Value: bson.D{{Key: "$gte", Value: lower}, {Key: "$lt", Value: upper}}}}
+
+	opts := options.Find()
+	if w.sort != 0 {
+		opts.SetSort(bson.D{{Key: w.field, Value: w.sort}})
+	}
+	if len(w.project) > 0 {
+		projection := bson.D{}
+		for _, field := range w.project {
+			projection = append(projection, bson.E{Key: field, Value: 1})
+		}
+		opts.SetProjection(projection)
+	}
+	if w.limit > 0 {
+		opts.SetLimit(w.limit)
+	}
+	if w.batch > 0 {
+		opts.SetBatchSize(w.batch)
+	}
+
+	start := time.Now()
+	cursor, err := collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return err
+	}
+	return w.stats.consume(cursor, start)
+}
+
+func (w *scanWorkload) queryStats() *QueryStats {
+	return w.stats
+}
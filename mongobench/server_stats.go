@@ -0,0 +1,202 @@
+package mongobench
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CommandRunner runs database commands, implemented by *mongo.Database
+type CommandRunner interface {
+	RunCommand(ctx context.Context, runCommand interface{}, opts ...*options.RunCmdOptions) *mongo.SingleResult
+}
+
+// serverStatsColumn describes one value sampled from serverStatus or collStats. Counters are reported as
+// per-second rates between two samples, everything else as the sampled value.
+type serverStatsColumn struct {
+	name    string
+	command string
+	path    []string
+	counter bool
+}
+
+var serverStatsColumns = []serverStatsColumn{
+	{"insert_per_sec", "serverStatus", []string{"opcounters", "insert"}, true},
+	{"query_per_sec", "serverStatus", []string{"opcounters", "query"}, true},
+	{"update_per_sec", "serverStatus", []string{"opcounters", "update"}, true},
+	{"delete_per_sec", "serverStatus", []string{"opcounters", "delete"}, true},
+	{"getmore_per_sec", "serverStatus", []string{"opcounters", "getmore"}, true},
+	{"command_per_sec", "serverStatus", []string{"opcounters", "command"}, true},
+	{"cache_bytes", "serverStatus", []string{"wiredTiger", "cache", "bytes currently in the cache"}, false},
+	{"cache_max_bytes", "serverStatus", []string{"wiredTiger", "cache", "maximum bytes configured"}, false},
+	{"cache_dirty_bytes", "serverStatus", []string{"wiredTiger", "cache", "tracked dirty bytes in the cache"}, false},
+	{"evicted_unmodified_per_sec", "serverStatus", []string{"wiredTiger", "cache", "unmodified pages evicted"}, true},
+	{"evicted_modified_per_sec", "serverStatus", []string{"wiredTiger", "cache", "modified pages evicted"}, true},
+	{"evicted_by_app_threads_per_sec", "serverStatus", []string{"wiredTiger", "cache", "pages evicted by application threads"}, true},
+	{"read_tickets_out", "serverStatus", []string{"queues", "execution", "read", "out"}, false},
+	{"read_tickets_available", "serverStatus", []string{"queues", "execution", "read", "available"}, false},
+	{"write_tickets_out", "serverStatus", []string{"queues", "execution", "write", "out"}, false},
+	{"write_tickets_available", "serverStatus", []string{"queues", "execution", "write", "available"}, false},
+	{"connections_current", "serverStatus", []string{"connections", "current"}, false},
+	{"connections_available", "serverStatus", []string{"connections", "available"}, false},
+	{"connections_created_per_sec", "serverStatus", []string{"connections", "totalCreated"}, true},
+	{"net_bytes_in_per_sec", "serverStatus", []string{"network", "bytesIn"}, true},
+	{"net_bytes_out_per_sec", "serverStatus", []string{"network", "bytesOut"}, true},
+	{"global_lock_queue_readers", "serverStatus", []string{"globalLock", "currentQueue", "readers"}, false},
+	{"global_lock_queue_writers", "serverStatus", []string{"globalLock", "currentQueue", "writers"}, false},
+	{"coll_count", "collStats", []string{"count"}, false},
+	{"coll_size", "collStats", []string{"size"}, false},
+	{"coll_storage_size", "collStats", []string{"storageSize"}, false},
+	{"coll_index_size", "collStats", []string{"totalIndexSize"}, false},
+	{"coll_indexes", "collStats", []string{"nindexes"}, false},
+}
+
+// legacyTicketPaths maps the execution ticket paths of MongoDB 7.0+ to their location in older server versions
+var legacyTicketPaths = map[string][]string{
+	"read_tickets_out":        {"wiredTiger", "concurrentTransactions", "read", "out"},
+	"read_tickets_available":  {"wiredTiger", "concurrentTransactions", "read", "available"},
+	"write_tickets_out":       {"wiredTiger", "concurrentTransactions", "write", "out"},
+	"write_tickets_available": {"wiredTiger", "concurrentTransactions", "write", "available"},
+}
+
+// ServerStatsSampler polls serverStatus and collStats in the background and keeps the samples as a time series
+// with the same epoch-second timestamps as the client metrics.
+type ServerStatsSampler struct {
+	db         CommandRunner
+	collection string
+	interval   time.Duration
+	loop       sampleLoop
+
+	previous     map[string]float64
+	previousTime time.Time
+	records      [][]string
+}
+
+func NewServerStatsSampler(db CommandRunner, collection string, interval time.Duration) *ServerStatsSampler {
+	header := []string{"t"}
+	for _, column := range serverStatsColumns {
+		header = append(header, column.name)
+	}
+	return &ServerStatsSampler{
+		db:         db,
+		collection: collection,
+		interval:   interval,
+		records:    [][]string{header},
+	}
+}
+
+// Start begins sampling at the configured interval
+func (s *ServerStatsSampler) Start() {
+	s.loop.start(s.interval, s.sample)
+}
+
+// Stop ends sampling and writes the collected time series to the given CSV file
+func (s *ServerStatsSampler) Stop(filename string) error {
+	s.loop.stop()
+	s.sample()
+
+	return Output{}.save("Server statistics", filename, s.records)
+}
+
+func (s *ServerStatsSampler) sample() {
+	now := time.Now()
+	docs := make(map[string]bson.M)
+	for _, command := range []bson.D{{{Key: "serverStatus", Value: 1}}, {{Key: "collStats", Value: s.collection}}} {
+		var doc bson.M
+		if err := s.db.RunCommand(context.Background(), command).Decode(&doc); err != nil {
+			logger.Printf("Failed to sample %s: %v", command[0].Key, err)
+		}
+		docs[command[0].Key] = doc
+	}
+
+	current := make(map[string]float64)
+	record := []string{fmt.Sprintf("%d", now.Unix())}
+	for _, column := range serverStatsColumns {
+		value, ok := lookupNumber(docs[column.command], column.path...)
+		if legacy, hasLegacy := legacyTicketPaths[column.name]; !ok && hasLegacy {
+			value, ok = lookupNumber(docs[column.command], legacy...)
+		}
+		if !ok {
+			record = append(record, "")
+			continue
+		}
+
+		current[column.name] = value
+		if !column.counter {
+			record = append(record, fmt.Sprintf("%.0f", value))
+			continue
+		}
+		previous, seen := s.previous[column.name]
+		elapsed := now.Sub(s.previousTime).Seconds()
+		if !seen || elapsed <= 0 {
+			record = append(record, "")
+			continue
+		}
+		record = append(record, fmt.Sprintf("%.2f", (value-previous)/elapsed))
+	}
+
+	s.previous = current
+	s.previousTime = now
+	s.records = append(s.records, record)
+}
+
+// lookupNumber returns the numeric value at the given path of nested documents
+func lookupNumber(doc bson.M, path ...string) (float64, bool) {
+	var value interface{} = doc
+	for _, key := range path {
+		nested, ok := value.(bson.M)
+		if !ok {
+			return 0, false
+		}
+		if value, ok = nested[key]; !ok {
+			return 0, false
+		}
+	}
+
+	switch v := value.(type) {
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// sampleLoop calls a sample function at a fixed interval in the background until it is stopped
+type sampleLoop struct {
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func (l *sampleLoop) start(interval time.Duration, sample func()) {
+	l.done = make(chan struct{})
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		sample()
+		for {
+			select {
+			case <-l.done:
+				return
+			case <-ticker.C:
+				sample()
+			}
+		}
+	}()
+}
+
+func (l *sampleLoop) stop() {
+	close(l.done)
+	l.wg.Wait()
+}
@@ -0,0 +1,393 @@
+package mongobench
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ParseShardKey parses a shard key like "rnd:hashed" or "threadRunCount:1,rnd:1"; fields without a direction are
+// ranged ascending
+func ParseShardKey(spec string) (bson.D, error) {
+	var key bson.D
+	for _, field := range SplitFields(spec) {
+		name, direction, _ := strings.Cut(field, ":")
+		switch direction {
+		case "", "1":
+			key = append(key, bson.E{Key: name, Value: 1})
+		case "hashed":
+			key = append(key, bson.E{Key: name, Value: "hashed"})
+		default:
+			return nil, fmt.Errorf("invalid direction %q of shard key field %s, expected 1 or hashed", direction, name)
+		}
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("the shard key has no fields")
+	}
+	return key, nil
+}
+
+// ShardingSetup shards the benchmark collection through mongos and optionally pre-splits it into evenly sized
+// chunks that are distributed across all shards, so the test does not measure the balancer catching up
+type ShardingSetup struct {
+	admin     CommandRunner
+	config    CommandRunner
+	namespace string
+	key       bson.D
+	chunks    int
+}
+
+// splittableFields are the ranged shard key fields the pre-split supports, as the generated values spread evenly over
+// the non-negative int64 values
+var splittableFields = map[string]bool{"rnd": true}
+
+// NewShardingSetup creates the setup for the given namespace; admin and config are the databases of the same name.
+// Pre-splitting a ranged key requires its first field to be one of the generated fields the split points suit.
+func NewShardingSetup(admin, config CommandRunner, namespace string, key bson.D, chunks int) (*ShardingSetup, error) {
+	if first := key[0]; chunks > 1 && first.Value != "hashed" && !splittableFields[first.Key] {
+		return nil, fmt.Errorf("cannot pre-split the ranged shard key field %s, use a hashed key or rnd as its first field", first.Key)
+	}
+	return &ShardingSetup{
+		admin:     admin,
+		config:    config,
+		namespace: namespace,
+		key:       key,
+		chunks:    chunks,
+	}, nil
+}
+
+// shard shards the collection unless it is sharded already, then pre-splits it if configured
+func (s *ShardingSetup) shard() error {
+	if collection, err := findShardedCollection(s.config, s.namespace); err != nil {
+		return fmt.Errorf("failed to look up the sharding state of %s: %v", s.namespace, err)
+	} else if collection != nil {
+		logger.Println("Collection is sharded already. Keeping its chunks.")
+		return nil
+	}
+
+	database, _, _ := strings.Cut(s.namespace, ".")
+	if err := runCommand(s.admin, bson.D{{Key: "enableSharding", Value: database}}); err != nil {
+		logger.Printf("Failed to enable sharding of %s: %v", database, err)
+	}
+	if err := runCommand(s.admin, bson.D{{Key: "shardCollection", Value: s.namespace}, {Key: "key", Value: s.key}}); err != nil {
+		return fmt.Errorf("failed to shard %s: %v", s.namespace, err)
+	}
+	logger.Printf("Sharded %s by %v", s.namespace, s.key)
+
+	if s.chunks > 1 {
+		return s.presplit()
+	}
+	return nil
+}
+
+// splitPoints returns the values of the first shard key field that divide it into evenly sized chunks. Hashed keys
+// are split over the whole hash space, ranged keys over the non-negative values the generated rnd field takes.
+func (s *ShardingSetup) splitPoints() []int64 {
+	var low int64
+	if s.key[0].Value == "hashed" {
+		low = math.MinInt64
+	}
+	step := math.MaxInt64/int64(s.chunks) - low/int64(s.chunks)
+	points := make([]int64, 0, s.chunks-1)
+	for i := 1; i < s.chunks; i++ {
+		points = append(points, low+int64(i)*step)
+	}
+	return points
+}
+
+// boundary returns the shard key value of a chunk boundary; fields after the first one are set to MinKey
+func (s *ShardingSetup) boundary(first interface{}, rest interface{}) bson.D {
+	boundary := bson.D{{Key: s.key[0].Key, Value: first}}
+	for _, field := range s.key[1:] {
+		boundary = append(boundary, bson.E{Key: field.Key, Value: rest})
+	}
+	return boundary
+}
+
+func (s *ShardingSetup) presplit() error {
+	points := s.splitPoints()
+	for _, point := range points {
+		if err := runCommand(s.admin, bson.D{{Key: "split", Value: s.namespace}, {Key: "middle", Value: s.boundary(point, primitive.MinKey{})}}); err != nil {
+			return fmt.Errorf("failed to split %s at %d: %v", s.namespace, point, err)
+		}
+	}
+
+	shards, err := listShards(s.admin)
+	if err != nil {
+		return fmt.Errorf("failed to list shards: %v", err)
+	}
+	lower := s.boundary(primitive.MinKey{}, primitive.MinKey{})
+	for i := 0; i <= len(points); i++ {
+		upper := s.boundary(primitive.MaxKey{}, primitive.MaxKey{})
+		if i < len(points) {
+			upper = s.boundary(points[i], primitive.MinKey{})
+		}
+		target := shards[i%len(shards)]
+		// Moving a chunk to the shard that owns it already fails, which is expected for some of the chunks
+		if err := runCommand(s.admin, bson.D{{Key: "moveChunk", Value: s.namespace}, {Key: "bounds", Value: bson.A{lower, upper}}, {Key: "to", Value: target}}); err != nil {
+			logger.Printf("Did not move chunk %d to %s: %v", i, target, err)
+		}
+		lower = upper
+	}
+	logger.Printf("Pre-split %s into %d chunks across %d shards", s.namespace, len(points)+1, len(shards))
+	return nil
+}
+
+// runCommand runs a command and returns its error
+func runCommand(db CommandRunner, command bson.D) error {
+	return db.RunCommand(context.Background(), command).Err()
+}
+
+// runCursorCommand runs a command returning a cursor, like find or aggregate, and returns its first batch
+func runCursorCommand(db CommandRunner, command bson.D) ([]bson.M, error) {
+	var result bson.M
+	if err := db.RunCommand(context.Background(), command).Decode(&result); err != nil {
+		return nil, err
+	}
+	cursor, _ := result["cursor"].(bson.M)
+	batch, _ := cursor["firstBatch"].(bson.A)
+	var docs []bson.M
+	for _, doc := range batch {
+		if doc, ok := doc.(bson.M); ok {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// findShardedCollection returns the config.collections entry of the namespace, or nil if it is not sharded
+func findShardedCollection(config CommandRunner, namespace string) (bson.M, error) {
+	docs, err := runCursorCommand(config, bson.D{{Key: "find", Value: "collections"}, {Key: "filter", Value: bson.M{"_id": namespace}}})
+	if err != nil || len(docs) == 0 || docs[0]["dropped"] == true {
+		return nil, err
+	}
+	return docs[0], nil
+}
+
+func listShards(admin CommandRunner) ([]string, error) {
+	var result struct {
+		Shards []struct {
+			ID string `bson:"_id"`
+		} `bson:"shards"`
+	}
+	if err := admin.RunCommand(context.Background(), bson.D{{Key: "listShards", Value: 1}}).Decode(&result); err != nil {
+		return nil, err
+	}
+	if len(result.Shards) == 0 {
+		return nil, fmt.Errorf("the cluster has no shards")
+	}
+	var shards []string
+	for _, shard := range result.Shards {
+		shards = append(shards, shard.ID)
+	}
+	return shards, nil
+}
+
+type shardStatsSample struct {
+	timestamp      int64
+	balancerRound  bool
+	balancerRounds float64
+	migrations     float64
+	opsPerSec      map[string]float64
+	docs           map[string]float64
+	chunks         map[string]float64
+}
+
+// ShardStatsSampler records how the operations and documents of the benchmark collection are distributed across the
+// shards, along with the chunks per shard, balancer rounds and chunk migrations during the run
+type ShardStatsSampler struct {
+	db         CommandRunner
+	admin      CommandRunner
+	config     CommandRunner
+	collection string
+	namespace  string
+	interval   time.Duration
+	loop       sampleLoop
+	started    time.Time
+
+	firstOps     map[string]float64
+	previousOps  map[string]float64
+	previousTime time.Time
+	samples      []shardStatsSample
+}
+
+// NewShardStatsSampler creates a sampler for the given collection of db, which must be accessed through mongos
+func NewShardStatsSampler(db, admin, config CommandRunner, database, collection string, interval time.Duration) *ShardStatsSampler {
+	return &ShardStatsSampler{
+		db:         db,
+		admin:      admin,
+		config:     config,
+		collection: collection,
+		namespace:  database + "." + collection,
+		interval:   interval,
+		firstOps:   make(map[string]float64),
+	}
+}
+
+// Start begins sampling at the configured interval
+func (s *ShardStatsSampler) Start() {
+	s.started = time.Now()
+	s.loop.start(s.interval, s.sample)
+}
+
+// Stop ends sampling, logs the share of the operations each shard served and writes the time series to filename
+func (s *ShardStatsSampler) Stop(filename string) error {
+	s.loop.stop()
+	s.sample()
+
+	totals := make(map[string]float64)
+	var sum float64
+	for shard, ops := range s.previousOps {
+		totals[shard] = ops - s.firstOps[shard]
+		sum += totals[shard]
+	}
+	if sum > 0 {
+		var shares []string
+		for _, shard := range sortedKeys(totals) {
+			shares = append(shares, fmt.Sprintf("%s %.1f%%", shard, 100*totals[shard]/sum))
+		}
+		logger.Printf("Operations per shard: %s", strings.Join(shares, ", "))
+	}
+	return s.writeCSV(filename)
+}
+
+func (s *ShardStatsSampler) sample() {
+	now := time.Now()
+	sample := shardStatsSample{
+		timestamp: now.Unix(),
+		opsPerSec: make(map[string]float64),
+		docs:      make(map[string]float64),
+		chunks:    make(map[string]float64),
+	}
+
+	// $collStats returns one document per shard when run through mongos
+	stats, err := runCursorCommand(s.db, bson.D{
+		{Key: "aggregate", Value: s.collection},
+		{Key: "pipeline", Value: bson.A{bson.M{"$collStats": bson.M{"latencyStats": bson.M{}, "count": bson.M{}}}}},
+		{Key: "cursor", Value: bson.M{}},
+	})
+	if err != nil {
+		logger.Printf("Failed to sample $collStats: %v", err)
+	}
+	ops := make(map[string]float64)
+	for _, doc := range stats {
+		shard, _ := doc["shard"].(string)
+		for _, kind := range []string{"reads", "writes", "commands"} {
+			value, _ := lookupNumber(doc, "latencyStats", kind, "ops")
+			ops[shard] += value
+		}
+		sample.docs[shard], _ = lookupNumber(doc, "count")
+	}
+	elapsed := now.Sub(s.previousTime).Seconds()
+	for shard, total := range ops {
+		if previous, ok := s.previousOps[shard]; ok && elapsed > 0 {
+			sample.opsPerSec[shard] = (total - previous) / elapsed
+		}
+		if _, ok := s.firstOps[shard]; !ok {
+			s.firstOps[shard] = total
+		}
+	}
+	s.previousOps = ops
+	s.previousTime = now
+
+	var balancer bson.M
+	if err := s.admin.RunCommand(context.Background(), bson.D{{Key: "balancerStatus", Value: 1}}).Decode(&balancer); err != nil {
+		logger.Printf("Failed to sample balancerStatus: %v", err)
+	}
+	sample.balancerRound, _ = balancer["inBalancerRound"].(bool)
+	sample.balancerRounds, _ = lookupNumber(balancer, "numBalancerRounds")
+
+	// Chunks reference their collection by namespace before MongoDB 5.0 and by UUID since
+	if collection, err := findShardedCollection(s.config, s.namespace); err != nil {
+		logger.Printf("Failed to look up the sharding state of %s: %v", s.namespace, err)
+	} else if collection != nil {
+		chunks, err := runCursorCommand(s.config, bson.D{
+			{Key: "aggregate", Value: "chunks"},
+			{Key: "pipeline", Value: bson.A{
+				bson.M{"$match": bson.M{"$or": bson.A{bson.M{"ns": s.namespace}, bson.M{"uuid": collection["uuid"]}}}},
+				bson.M{"$group": bson.M{"_id": "$shard", "chunks": bson.M{"$sum": 1}}},
+			}},
+			{Key: "cursor", Value: bson.M{}},
+		})
+		if err != nil {
+			logger.Printf("Failed to count chunks: %v", err)
+		}
+		for _, doc := range chunks {
+			shard, _ := doc["_id"].(string)
+			sample.chunks[shard], _ = lookupNumber(doc, "chunks")
+		}
+	}
+
+	var migrations bson.M
+	err = s.config.RunCommand(context.Background(), bson.D{
+		{Key: "count", Value: "changelog"},
+		{Key: "query", Value: bson.M{"what": "moveChunk.commit", "ns": s.namespace, "time": bson.M{"$gte": s.started}}},
+	}).Decode(&migrations)
+	if err != nil {
+		logger.Printf("Failed to count chunk migrations: %v", err)
+	}
+	sample.migrations, _ = lookupNumber(migrations, "n")
+
+	s.samples = append(s.samples, sample)
+}
+
+func (s *ShardStatsSampler) writeCSV(filename string) error {
+	shardSet := make(map[string]float64)
+	for _, sample := range s.samples {
+		for shard := range sample.docs {
+			shardSet[shard] = 0
+		}
+		for shard := range sample.chunks {
+			shardSet[shard] = 0
+		}
+	}
+	shards := sortedKeys(shardSet)
+
+	header := []string{"t", "balancer_in_round", "balancer_rounds", "migrations"}
+	for _, shard := range shards {
+		header = append(header, "ops_per_sec_"+shard, "ops_share_"+shard, "docs_"+shard, "chunks_"+shard)
+	}
+	records := [][]string{header}
+	for _, sample := range s.samples {
+		var sum float64
+		for _, rate := range sample.opsPerSec {
+			sum += rate
+		}
+		record := []string{
+			fmt.Sprintf("%d", sample.timestamp),
+			strconv.FormatBool(sample.balancerRound),
+			fmt.Sprintf("%.0f", sample.balancerRounds),
+			fmt.Sprintf("%.0f", sample.migrations),
+		}
+		for _, shard := range shards {
+			rate, ok := sample.opsPerSec[shard]
+			if !ok {
+				record = append(record, "", "")
+			} else if sum > 0 {
+				record = append(record, fmt.Sprintf("%.2f", rate), fmt.Sprintf("%.4f", rate/sum))
+			} else {
+				record = append(record, fmt.Sprintf("%.2f", rate), "")
+			}
+			record = append(record, fmt.Sprintf("%.0f", sample.docs[shard]), fmt.Sprintf("%.0f", sample.chunks[shard]))
+		}
+		records = append(records, record)
+	}
+
+	return Output{}.save("Shard statistics", filename, records)
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
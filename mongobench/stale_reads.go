@@ -0,0 +1,272 @@
+package mongobench
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+func init() {
+	RegisterWorkload(WorkloadDefinition{Name: "staleReads", Fresh: true, New: newStaleReadWorkload})
+}
+
+// ReadConfig is a read concern and read preference the staleReads test reads with
+type ReadConfig struct {
+	Concern    string // empty for the server's default read concern
+	Preference string // empty for primary
+}
+
+func (c ReadConfig) String() string {
+	concern, preference := c.Concern, c.Preference
+	if concern == "" {
+		concern = "default"
+	}
+	if preference == "" {
+		preference = "primary"
+	}
+	return concern + "/" + preference
+}
+
+// ParseReadConfigs parses a comma-separated list of read configurations in the form concern:preference, e.g.
+// "majority:secondary,local:primary"; either part may be empty
+func ParseReadConfigs(value string) ([]ReadConfig, error) {
+	var configs []ReadConfig
+	for _, field := range SplitFields(value) {
+		concern, preference, _ := strings.Cut(field, ":")
+		config := ReadConfig{Concern: strings.TrimSpace(concern), Preference: strings.TrimSpace(preference)}
+		if _, _, err := config.options(); err != nil {
+			return nil, err
+		}
+		configs = append(configs, config)
+	}
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no read configuration in %q", value)
+	}
+	return configs, nil
+}
+
+func (c ReadConfig) options() (*readconcern.ReadConcern, *readpref.ReadPref, error) {
+	var concern *readconcern.ReadConcern
+	switch c.Concern {
+	case "":
+	case "local", "available", "majority", "linearizable", "snapshot":
+		concern = &readconcern.ReadConcern{Level: c.Concern}
+	default:
+		return nil, nil, fmt.Errorf("unknown read concern %q", c.Concern)
+	}
+	if c.Preference == "" {
+		return concern, readpref.Primary(), nil
+	}
+	mode, err := readpref.ModeFromString(c.Preference)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unknown read preference %q", c.Preference)
+	}
+	preference, err := readpref.New(mode)
+	if err != nil {
+		return nil, nil, err
+	}
+	return concern, preference, nil
+}
+
+// acknowledgedWrite is a write of a key in the history of the staleReads test
+type acknowledgedWrite struct {
+	version int64
+	acked   time.Time
+}
+
+// observedRead is a read of a key in the history of the staleReads test
+type observedRead struct {
+	key     int
+	config  int
+	version int64
+	invoked time.Time
+}
+
+// staleReadWorker is the state of a worker of the staleReads test, only accessed by the worker itself
+type staleReadWorker struct {
+	readers    []CollectionAPI
+	owned      []int
+	nextConfig int
+	reads      []observedRead
+}
+
+// staleReadWorkload writes increasing versions of a small set of keys and reads them back with every read
+// configuration. Every key is written by a single worker, so its versions are acknowledged in order. After the test,
+// the history is checked for reads that returned an older version than one acknowledged before the read started.
+type staleReadWorkload struct {
+	BaseWorkload
+	env       WorkloadEnv
+	configs   []ReadConfig
+	readShare float64
+	versions  []int64
+	writes    [][]acknowledgedWrite
+	workers   []*staleReadWorker
+}
+
+func newStaleReadWorkload(env WorkloadEnv) (Workload, error) {
+	configs := env.Config.ReadConfigs
+	if len(configs) == 0 {
+		configs = []ReadConfig{{}}
+	}
+	keys := env.Config.StaleReadKeys
+	if keys <= 0 {
+		keys = 100
+	}
+	readShare := 0.8
+	if env.Config.ReadShare != nil {
+		readShare = *env.Config.ReadShare
+	}
+	if readShare < 0 || readShare > 1 {
+		return nil, fmt.Errorf("invalid read share %v, expected a share between 0 and 1", readShare)
+	}
+	w := &staleReadWorkload{
+		env:       env,
+		configs:   configs,
+		readShare: readShare,
+		versions:  make([]int64, keys),
+		writes:    make([][]acknowledgedWrite, keys),
+		workers:   make([]*staleReadWorker, env.Config.Threads),
+	}
+	for i := range w.workers {
+		w.workers[i] = &staleReadWorker{}
+		for key := i; key < keys; key += env.Config.Threads {
+			w.workers[i].owned = append(w.workers[i].owned, key)
+		}
+	}
+	return w, nil
+}
+
+// Setup writes the first version of every key
+func (w *staleReadWorkload) Setup(collection CollectionAPI) error {
+	for key := range w.versions {
+		err := w.env.Config.Retry.run(w.env.RetryStats, func() error {
+			_, err := collection.ReplaceOne(context.Background(), bson.M{"_id": key}, bson.M{"_id": key, "version": int64(0)}, options.Replace().SetUpsert(true))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write key %d: %v", key, err)
+		}
+	}
+	logger.Printf("Reading with %d read configurations from %d keys", len(w.configs), len(w.versions))
+	return nil
+}
+
+// Run writes the next version of a key the worker owns, or reads a key with the worker's next read configuration
+func (w *staleReadWorkload) Run(collection CollectionAPI, worker *Worker) error {
+	state := w.workers[worker.ID]
+	if state.readers == nil {
+		for _, config := range w.configs {
+			reader, err := collectionWithRead(collection, config)
+			if err != nil {
+				return err
+			}
+			state.readers = append(state.readers, reader)
+		}
+	}
+
+	if len(state.owned) > 0 && worker.Random.RandomFloat64() >= w.readShare {
+		key := state.owned[worker.Random.RandomIntn(len(state.owned))]
+		w.versions[key]++
+		version := w.versions[key]
+		err := w.env.Config.Retry.run(w.env.RetryStats, func() error {
+			_, err := collection.UpdateOne(context.Background(), bson.M{"_id": key}, bson.M{"$set": bson.M{"version": version}})
+			return err
+		})
+		if err == nil {
+			w.writes[key] = append(w.writes[key], acknowledgedWrite{version: version, acked: time.Now()})
+		}
+		return err
+	}
+
+	key := worker.Random.RandomIntn(len(w.versions))
+	config := state.nextConfig
+	state.nextConfig = (state.nextConfig + 1) % len(w.configs)
+	invoked := time.Now()
+	var doc struct {
+		Version int64 `bson:"version"`
+	}
+	cursor, err := state.readers[config].Find(context.Background(), bson.M{"_id": key}, options.Find().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(context.Background())
+	if !cursor.Next(context.Background()) {
+		if err := cursor.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("key %d not found with %s", key, w.configs[config])
+	}
+	if err := cursor.Decode(&doc); err != nil {
+		return err
+	}
+	state.reads = append(state.reads, observedRead{key: key, config: config, version: doc.Version, invoked: invoked})
+	return nil
+}
+
+// staleReadStats are the results of the history check for one read configuration
+type staleReadStats struct {
+	reads        int64
+	stale        int64
+	staleness    time.Duration // how long the returned versions had been outdated, in total
+	maxStaleness time.Duration
+}
+
+// check finds the reads that returned an older version than the last one acknowledged before the read started
+func (w *staleReadWorkload) check() []staleReadStats {
+	stats := make([]staleReadStats, len(w.configs))
+	for _, worker := range w.workers {
+		for _, read := range worker.reads {
+			writes := w.writes[read.key]
+			stats[read.config].reads++
+			// The writes of a key are acknowledged in order, so the last one before the read is the newest
+			acked := sort.Search(len(writes), func(i int) bool { return !writes[i].acked.Before(read.invoked) })
+			if acked == 0 || writes[acked-1].version <= read.version {
+				continue
+			}
+			newer := sort.Search(acked, func(i int) bool { return writes[i].version > read.version })
+			staleness := read.invoked.Sub(writes[newer].acked)
+			stats[read.config].stale++
+			stats[read.config].staleness += staleness
+			stats[read.config].maxStaleness = max(stats[read.config].maxStaleness, staleness)
+		}
+	}
+	return stats
+}
+
+// Teardown checks the history and reports the stale reads of every read configuration
+func (w *staleReadWorkload) Teardown(CollectionAPI) error {
+	records := [][]string{{"read_concern", "read_preference", "reads", "stale_reads", "stale_share", "staleness_mean_ms", "staleness_max_ms"}}
+	for i, stats := range w.check() {
+		share, mean := 0.0, 0.0
+		if stats.reads > 0 {
+			share = float64(stats.stale) / float64(stats.reads)
+		}
+		if stats.stale > 0 {
+			mean = float64(stats.staleness) / float64(stats.stale)
+		}
+		logger.Printf("Stale reads with %s: %d of %d reads, staleness mean %s ms, max %s ms",
+			w.configs[i], stats.stale, stats.reads, formatMillis(mean), formatMillis(float64(stats.maxStaleness)))
+		concern, preference, _ := strings.Cut(w.configs[i].String(), "/")
+		records = append(records, []string{
+			concern,
+			preference,
+			fmt.Sprintf("%d", stats.reads),
+			fmt.Sprintf("%d", stats.stale),
+			fmt.Sprintf("%.6f", share),
+			formatMillis(mean),
+			formatMillis(float64(stats.maxStaleness)),
+		})
+	}
+	filename := "stale_reads.csv"
+	if w.env.Config.RunLabel != "" {
+		filename = fmt.Sprintf("stale_reads_%s.csv", w.env.Config.RunLabel)
+	}
+	return w.env.Config.Output.save("Stale read report", filename, records)
+}
@@ -0,0 +1,302 @@
+package mongobench
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+type TestingConfig struct {
+	Threads   int
+	DocCount  int
+	Duration  int
+	LargeDocs bool
+	DropDb    bool
+	Retry     RetryPolicy
+	Gate      *Gate
+	Metrics   []MetricsSource
+	Output    Output
+
+	PipelineFile string
+	AllowDiskUse bool
+	BatchSize    int32
+
+	ScanField      string
+	ScanMin        int64
+	ScanMax        int64
+	ScanWidth      int64
+	ScanSort       int
+	ScanProjection []string
+	ScanLimit      int64
+
+	Indexes         []mongo.IndexModel
+	IndexBuild      []mongo.IndexModel
+	IndexBuildDelay time.Duration
+	RunLabel        string
+
+	Watchers      int
+	WatchPipeline bson.A
+	FullDocument  string
+
+	TimeSeries *options.TimeSeriesOptions
+	Sensors    *SensorFleet
+	WindowSize time.Duration
+
+	Sharding *ShardingSetup
+
+	QueueItems int
+	ThinkTime  time.Duration
+
+	Buckets int
+
+	Updates         *UpdateMix
+	ReplaceMinBytes int
+	ReplaceMaxBytes int
+
+	RefillThreads int
+
+	ReadConfigs   []ReadConfig
+	StaleReadKeys int
+	ReadShare     *float64 // share of the staleReads operations that are reads, 0.8 if unset
+
+	Stop     StopConditions
+	Verify   bool             // read back the written documents after each test
+	Failover *FailoverTracker // reports primary changes during each test, nil if disabled
+
+	DriverRetries *DriverRetries // counts the driver's retries next to the application-level ones, nil if disabled
+}
+
+// TestResult summarizes a finished test
+type TestResult struct {
+	TestType    string
+	Count       int64
+	Duration    time.Duration
+	MeanRate    float64
+	LatencyMean time.Duration
+	LatencyP99  time.Duration
+	StopReason  string
+	// Verification is the outcome of reading back the written documents, if enabled with TestingConfig.Verify
+	Verification *Verification
+	// Failover reports the unavailability during the test if the primary changed, with TestingConfig.Failover set
+	Failover *FailoverReport
+}
+
+// prepareCollection drops the collection if the test starts from scratch and dropping is enabled, creates it as a
+// time-series collection and shards it if configured, then creates the declared indexes. If an index build is part of
+// the test, existing secondary indexes are dropped so the build starts from scratch.
+func prepareCollection(collection CollectionAPI, config TestingConfig, drop bool) error {
+	dropped := false
+	if drop {
+		if config.DropDb {
+			if err := collection.Drop(context.Background()); err != nil {
+				return fmt.Errorf("failed to drop collection: %v", err)
+			}
+			dropped = true
+			logger.Println("Collection dropped. Starting new rate test...")
+		} else {
+			logger.Println("Collection stays. Dropping disabled.")
+		}
+	}
+	if config.TimeSeries != nil {
+		if err := createTimeSeriesCollection(collection, config.TimeSeries); err != nil {
+			return err
+		}
+	}
+	if config.Sharding != nil {
+		if err := config.Sharding.shard(); err != nil {
+			return err
+		}
+	}
+	if len(config.IndexBuild) > 0 && !dropped {
+		if err := collection.DropIndexes(context.Background()); err != nil && !isNamespaceNotFound(err) {
+			return fmt.Errorf("failed to drop indexes: %v", err)
+		}
+	}
+	return createIndexes(collection, config)
+}
+
+// resultsFilename returns the CSV file the per-second metrics of the test are saved to
+func (c TestingConfig) resultsFilename(testType string) string {
+	if c.RunLabel != "" {
+		return fmt.Sprintf("benchmark_results_%s_%s.csv", testType, c.RunLabel)
+	}
+	return fmt.Sprintf("benchmark_results_%s.csv", testType)
+}
+
+// workerCollections is implemented by collections that give each worker its own client
+type workerCollections interface {
+	ForWorker(worker int) CollectionAPI
+}
+
+// collectionForWorker returns the collection the given worker should run its operations against
+func collectionForWorker(collection CollectionAPI, worker int) CollectionAPI {
+	if provider, ok := collection.(workerCollections); ok {
+		return provider.ForWorker(worker)
+	}
+	return collection
+}
+
+// readCollections is implemented by collections that can read with another read concern and read preference
+type readCollections interface {
+	WithRead(concern *readconcern.ReadConcern, preference *readpref.ReadPref) (CollectionAPI, error)
+}
+
+// collectionWithRead returns the collection reading with the given read configuration. Collections without replicas,
+// like the in-memory one, always read the latest writes and are returned as they are.
+func collectionWithRead(collection CollectionAPI, config ReadConfig) (CollectionAPI, error) {
+	provider, ok := collection.(readCollections)
+	if !ok {
+		return collection, nil
+	}
+	concern, preference, err := config.options()
+	if err != nil {
+		return nil, err
+	}
+	return provider.WithRead(concern, preference)
+}
+
+// queryWorkload is a read test that runs without previously fetched document IDs
+type queryWorkload interface {
+	run(collection CollectionAPI, r *Randomizer) error
+	queryStats() *QueryStats
+}
+
+// queryTest runs a queryWorkload as a Workload; the query is prepared in Setup as it may need the collection
+type queryTest struct {
+	BaseWorkload
+	env      WorkloadEnv
+	newQuery func(collection CollectionAPI, config TestingConfig) (queryWorkload, error)
+	query    queryWorkload
+}
+
+func newQueryTest(newQuery func(CollectionAPI, TestingConfig) (queryWorkload, error)) func(env WorkloadEnv) (Workload, error) {
+	return func(env WorkloadEnv) (Workload, error) {
+		return &queryTest{env: env, newQuery: newQuery}, nil
+	}
+}
+
+func (w *queryTest) Setup(collection CollectionAPI) error {
+	var err error
+	w.query, err = w.newQuery(collection, w.env.Config)
+	return err
+}
+
+func (w *queryTest) Run(collection CollectionAPI, worker *Worker) error {
+	return w.env.Config.Retry.run(w.env.RetryStats, func() error {
+		return w.query.run(collection, worker.Random)
+	})
+}
+
+// Columns implements MetricsSource
+func (w *queryTest) Columns() []string {
+	return w.query.queryStats().Columns()
+}
+
+// Values implements MetricsSource
+func (w *queryTest) Values() []string {
+	return w.query.queryStats().Values()
+}
+
+// metricsSources returns the per-test metrics sources that are enabled by the configuration
+func (c TestingConfig) metricsSources(retryStats *RetryStats, workloadSources ...MetricsSource) []MetricsSource {
+	sources := append([]MetricsSource{}, c.Metrics...)
+	if c.Retry.MaxAttempts > 1 {
+		sources = append(sources, retryStats)
+	}
+	if c.DriverRetries != nil {
+		sources = append(sources, c.DriverRetries)
+	}
+	return append(sources, workloadSources...)
+}
+
+// Gate blocks workers while it is closed, e.g. to let lagging secondaries catch up. A failed gate stops the running
+// test and fails it and every following one. A nil Gate never blocks.
+type Gate struct {
+	mu     sync.Mutex
+	closed chan struct{} // closed when the gate opens again, nil while it is open
+	failed chan struct{} // closed when the gate fails
+	err    error
+}
+
+func NewGate() *Gate {
+	return &Gate{failed: make(chan struct{})}
+}
+
+// Wait blocks until the gate is open and reports true, or reports false once stopped is closed first
+func (g *Gate) Wait(stopped <-chan struct{}) bool {
+	if g == nil {
+		return true
+	}
+	for {
+		g.mu.Lock()
+		closed := g.closed
+		g.mu.Unlock()
+		if closed == nil {
+			return true
+		}
+		select {
+		case <-closed:
+		case <-stopped:
+			return false
+		}
+	}
+}
+
+// Err returns the error the gate failed with, if any
+func (g *Gate) Err() error {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err
+}
+
+// failure returns a channel that is closed once the gate fails; a nil Gate never fails
+func (g *Gate) failure() <-chan struct{} {
+	if g == nil {
+		return nil
+	}
+	return g.failed
+}
+
+// fail stops the running test; only the first error is kept
+func (g *Gate) fail(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.err == nil {
+		g.err = err
+		close(g.failed)
+	}
+}
+
+func (g *Gate) set(closed bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	switch {
+	case closed && g.closed == nil:
+		g.closed = make(chan struct{})
+	case !closed && g.closed != nil:
+		close(g.closed)
+		g.closed = nil
+	}
+}
+
+// SplitFields parses a comma-separated list of field names
+func SplitFields(list string) []string {
+	var fields []string
+	for _, field := range strings.Split(list, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,171 @@
+package mongobench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var sensorSites = []string{"berlin", "hamburg", "munich", "cologne", "frankfurt"}
+
+// SensorFleet generates the measurements of simulated sensors. Every sensor reports once per interval, with some
+// jitter, starting at the creation of the fleet, so the timestamps advance like those of a real fleet of sensors
+// regardless of how fast the benchmark writes them.
+type SensorFleet struct {
+	sensors   int
+	interval  time.Duration
+	start     time.Time
+	timeField string
+	metaField string
+	sequence  atomic.Int64
+}
+
+func NewSensorFleet(sensors int, interval time.Duration, timeField, metaField string) *SensorFleet {
+	return &SensorFleet{
+		sensors:   sensors,
+		interval:  interval,
+		start:     time.Now().Truncate(time.Second),
+		timeField: timeField,
+		metaField: metaField,
+	}
+}
+
+// sensorID returns the ID stored in the metadata of the given sensor
+func sensorID(sensor int) string {
+	return fmt.Sprintf("sensor-%05d", sensor)
+}
+
+// measurement generates the next measurement; the sensors take turns, so all of them advance at the same pace
+func (f *SensorFleet) measurement(r *Randomizer) bson.M {
+	sequence := f.sequence.Add(1) - 1
+	sensor := int(sequence % int64(f.sensors))
+	tick := sequence / int64(f.sensors)
+
+	timestamp := f.start.Add(time.Duration(tick) * f.interval)
+	if jitter := int64(f.interval) / 10; jitter > 0 {
+		timestamp = timestamp.Add(time.Duration(r.RandomInt63n(jitter)))
+	}
+
+	return bson.M{
+		f.timeField: timestamp,
+		f.metaField: bson.M{"id": sensorID(sensor), "site": sensorSites[sensor%len(sensorSites)]},
+		// Each sensor fluctuates around its own baseline
+		"temperature": 15 + float64(sensor%20) + r.RandomFloat64()*4 - 2,
+		"humidity":    30 + float64(sensor%50) + r.RandomFloat64()*10 - 5,
+		"pressure":    1013 + r.RandomFloat64()*20 - 10,
+	}
+}
+
+// createTimeSeriesCollection creates the collection as a time-series collection; an existing collection is kept
+func createTimeSeriesCollection(collection CollectionAPI, timeSeries *options.TimeSeriesOptions) error {
+	err := collection.CreateCollection(context.Background(), options.CreateCollection().SetTimeSeriesOptions(timeSeries))
+	if isNamespaceExists(err) {
+		logger.Println("Collection exists already. Keeping it as it is.")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create time-series collection: %v", err)
+	}
+	logger.Printf("Created time-series collection with time field %s", timeSeries.TimeField)
+	return nil
+}
+
+func isNamespaceExists(err error) bool {
+	var commandErr mongo.CommandError
+	return errors.As(err, &commandErr) && commandErr.Code == 48
+}
+
+func init() {
+	RegisterWorkload(WorkloadDefinition{Name: "window", New: newQueryTest(func(collection CollectionAPI, config TestingConfig) (queryWorkload, error) {
+		return newWindowWorkload(collection, config)
+	})})
+}
+
+// windowWorkload runs the typical dashboard query against the measurements of the sensor fleet: the minimum, mean
+// and maximum temperature of one sensor over a time window, downsampled into 60 points
+type windowWorkload struct {
+	fleet  *SensorFleet
+	window time.Duration
+	bin    int64
+	first  time.Time
+	span   time.Duration
+	stats  *QueryStats
+}
+
+func newWindowWorkload(collection CollectionAPI, config TestingConfig) (*windowWorkload, error) {
+	if config.Sensors == nil {
+		return nil, fmt.Errorf("the window test requires simulated sensors")
+	}
+	fleet := config.Sensors
+
+	// The window is placed within the measurements written by previous tests
+	cursor, err := collection.Aggregate(context.Background(), bson.A{
+		bson.M{"$group": bson.M{"_id": nil, "first": bson.M{"$min": "$" + fleet.timeField}, "last": bson.M{"$max": "$" + fleet.timeField}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine the time range of the measurements: %v", err)
+	}
+	var bounds []struct {
+		First time.Time `bson:"first"`
+		Last  time.Time `bson:"last"`
+	}
+	if err := cursor.All(context.Background(), &bounds); err != nil {
+		return nil, fmt.Errorf("failed to determine the time range of the measurements: %v", err)
+	}
+	if len(bounds) == 0 || bounds[0].First.IsZero() {
+		return nil, fmt.Errorf("the collection holds no measurements, run the insert test with sensors first")
+	}
+
+	span := bounds[0].Last.Sub(bounds[0].First) - config.WindowSize
+	if span < 0 {
+		span = 0
+	}
+	bin := int64(config.WindowSize / time.Second / 60)
+	if bin < 1 {
+		bin = 1
+	}
+	return &windowWorkload{
+		fleet:  fleet,
+		window: config.WindowSize,
+		bin:    bin,
+		first:  bounds[0].First,
+		span:   span,
+		stats:  NewQueryStats(),
+	}, nil
+}
+
+// run queries a random window of a random sensor
+func (w *windowWorkload) run(collection CollectionAPI, r *Randomizer) error {
+	from := w.first.Add(time.Duration(r.RandomInt63n(int64(w.span) + 1)))
+	timeField := "$" + w.fleet.timeField
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{
+			w.fleet.metaField + ".id": sensorID(r.RandomIntn(w.fleet.sensors)),
+			w.fleet.timeField:         bson.M{"$gte": from, "$lt": from.Add(w.window)},
+		}},
+		bson.M{"$group": bson.M{
+			"_id": bson.M{"$dateTrunc": bson.M{"date": timeField, "unit": "second", "binSize": w.bin}},
+			"min": bson.M{"$min": "$temperature"},
+			"avg": bson.M{"$avg": "$temperature"},
+			"max": bson.M{"$max": "$temperature"},
+		}},
+		bson.M{"$sort": bson.M{"_id": 1}},
+	}
+
+	start := time.Now()
+	cursor, err := collection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return err
+	}
+	return w.stats.consume(cursor, start)
+}
+
+func (w *windowWorkload) queryStats() *QueryStats {
+	return w.stats
+}
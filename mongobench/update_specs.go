@@ -0,0 +1,150 @@
+package mongobench
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// updateSpecFile is the declaration of one update in the update file. The update is either a document of update
+// operators or an array of pipeline stages, both may contain generator placeholders.
+type updateSpecFile struct {
+	Weight       *int        `bson:"weight"` // 1 if missing, 0 disables the update
+	Update       interface{} `bson:"update"`
+	ArrayFilters bson.A      `bson:"arrayFilters"`
+	Grow         int         `bson:"grow"`
+}
+
+type updateSpec struct {
+	weight       int
+	update       *Template
+	pipeline     bool
+	arrayFilters bson.A
+	grow         int
+}
+
+// UpdateMix picks the updates of the update, upsert and updateMany tests from weighted specifications
+type UpdateMix struct {
+	specs []updateSpec
+	total int
+}
+
+// LoadUpdateMix reads the weighted update specifications of an Extended JSON file
+func LoadUpdateMix(path string) (*UpdateMix, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read update file: %v", err)
+	}
+	var files []updateSpecFile
+	if err := bson.UnmarshalExtJSON(data, false, &files); err != nil {
+		return nil, fmt.Errorf("failed to parse update file %s: %v", path, err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("update file %s is empty", path)
+	}
+
+	mix := &UpdateMix{}
+	for i, file := range files {
+		weight := 1
+		if file.Weight != nil {
+			weight = *file.Weight
+		}
+		if weight < 0 || file.Grow < 0 {
+			return nil, fmt.Errorf("update %d in %s has a negative weight or growth", i, path)
+		}
+		_, pipeline := file.Update.(bson.A)
+		if _, operators := file.Update.(bson.D); !operators && !pipeline {
+			return nil, fmt.Errorf("update %d in %s is neither a document nor a pipeline", i, path)
+		}
+		update, err := CompileTemplate(file.Update)
+		if err != nil {
+			return nil, fmt.Errorf("invalid update %d in %s: %v", i, path, err)
+		}
+		if weight == 0 {
+			continue
+		}
+		mix.specs = append(mix.specs, updateSpec{
+			weight:       weight,
+			update:       update,
+			pipeline:     pipeline,
+			arrayFilters: file.ArrayFilters,
+			grow:         file.Grow,
+		})
+		mix.total += weight
+	}
+	if mix.total == 0 {
+		return nil, fmt.Errorf("update file %s has no update with a positive weight", path)
+	}
+	return mix, nil
+}
+
+// next picks an update by weight and generates it; set holds fields the benchmark itself sets on every update
+func (m *UpdateMix) next(r *Randomizer, set bson.D) (interface{}, *options.UpdateOptions) {
+	pick := r.RandomIntn(m.total)
+	spec := m.specs[0]
+	for _, candidate := range m.specs {
+		if pick < candidate.weight {
+			spec = candidate
+			break
+		}
+		pick -= candidate.weight
+	}
+
+	opts := options.Update()
+	if spec.arrayFilters != nil {
+		opts.SetArrayFilters(options.ArrayFilters{Filters: spec.arrayFilters})
+	}
+	if spec.pipeline {
+		pipeline := spec.update.Generate(r).(bson.A)
+		if len(set) > 0 {
+			pipeline = append(pipeline, bson.D{{Key: "$set", Value: set}})
+		}
+		if spec.grow > 0 {
+			growth := bson.D{{Key: "$concatArrays", Value: bson.A{bson.D{{Key: "$ifNull", Value: bson.A{"$growth", bson.A{}}}}, bson.A{r.RandomBytes(spec.grow)}}}}
+			pipeline = append(pipeline, bson.D{{Key: "$set", Value: bson.D{{Key: "growth", Value: growth}}}})
+		}
+		return pipeline, opts
+	}
+
+	update := spec.update.Generate(r).(bson.D)
+	for _, field := range set {
+		update = withOperator(update, "$set", field)
+	}
+	if spec.grow > 0 {
+		update = withOperator(update, "$push", bson.E{Key: "growth", Value: r.RandomBytes(spec.grow)})
+	}
+	return update, opts
+}
+
+// withOperator adds a field to an update operator of the update document, merging it with the fields the
+// specification already declares for that operator
+func withOperator(update bson.D, operator string, field bson.E) bson.D {
+	for i, element := range update {
+		if element.Key != operator {
+			continue
+		}
+		if fields, ok := element.Value.(bson.D); ok {
+			update[i].Value = append(fields, field)
+			return update
+		}
+	}
+	return append(update, bson.E{Key: operator, Value: bson.D{field}})
+}
+
+// newReplacement generates the replacement document of the replace test, with a payload of a random size within
+// the configured bounds so that replacements change the document size
+func newReplacement(threadID int, r *Randomizer, config TestingConfig, data []byte) bson.M {
+	doc := newDocument(threadID, r, config, data)
+	doc["replacedAt"] = time.Now().Unix()
+	if config.ReplaceMaxBytes > 0 {
+		size := config.ReplaceMinBytes
+		if config.ReplaceMaxBytes > config.ReplaceMinBytes {
+			size += r.RandomIntn(config.ReplaceMaxBytes - config.ReplaceMinBytes + 1)
+		}
+		doc["payload"] = r.RandomBytes(size)
+	}
+	return doc
+}
@@ -0,0 +1,111 @@
+package mongobench
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func init() {
+	RegisterWorkload(WorkloadDefinition{Name: "update", New: newUpdateWorkload(false)})
+	RegisterWorkload(WorkloadDefinition{Name: "replace", New: newUpdateWorkload(true)})
+	RegisterWorkload(WorkloadDefinition{Name: "upsert", Fresh: true, New: newUpsertWorkload})
+}
+
+// updateWorkload updates or replaces random documents out of existing ones; each worker picks from its own partition
+type updateWorkload struct {
+	BaseWorkload
+	env        WorkloadEnv
+	replace    bool
+	partitions [][]primitive.ObjectID
+}
+
+func newUpdateWorkload(replace bool) func(env WorkloadEnv) (Workload, error) {
+	return func(env WorkloadEnv) (Workload, error) {
+		return &updateWorkload{env: env, replace: replace}, nil
+	}
+}
+
+func (w *updateWorkload) Setup(collection CollectionAPI) error {
+	testType := "update"
+	if w.replace {
+		testType = "replace"
+	}
+	docIDs, err := w.env.FetchDocIDs(collection, int64(w.env.Config.DocCount), testType)
+	if err != nil {
+		return fmt.Errorf("failed to fetch document IDs: %v", err)
+	}
+	if len(docIDs) == 0 {
+		return fmt.Errorf("no document IDs found for %s operations", testType)
+	}
+	w.partitions = partitionIDs(docIDs, w.env.Config.Threads)
+	return nil
+}
+
+func (w *updateWorkload) Run(collection CollectionAPI, worker *Worker) error {
+	partition := w.partitions[worker.ID]
+	if len(partition) == 0 {
+		return ErrStopWorker
+	}
+	id := partition[worker.Random.RandomIntn(len(partition))]
+	filter := bson.M{"_id": id}
+	if w.replace {
+		replacement := newReplacement(worker.ID, worker.Random, w.env.Config, w.env.Data)
+		err := w.env.Config.Retry.run(w.env.RetryStats, func() error {
+			_, err := collection.ReplaceOne(context.Background(), filter, replacement)
+			return err
+		})
+		w.env.Writes.Wrote(id, replacement, err)
+		return err
+	}
+	update, opts := newUpdate(worker.Random, w.env.Config)
+	err := w.env.Config.Retry.run(w.env.RetryStats, func() error {
+		_, err := collection.UpdateOne(context.Background(), filter, update, opts)
+		return err
+	})
+	w.env.Writes.Updated(id, update, err)
+	return err
+}
+
+// upsertWorkload upserts a fixed range of new IDs repeatedly, the first upsert of an ID inserts the document
+type upsertWorkload struct {
+	BaseWorkload
+	env        WorkloadEnv
+	partitions [][]primitive.ObjectID
+}
+
+func newUpsertWorkload(env WorkloadEnv) (Workload, error) {
+	ids := make([]primitive.ObjectID, env.Config.DocCount)
+	for i := range ids {
+		ids[i] = primitive.NewObjectID()
+	}
+	return &upsertWorkload{env: env, partitions: partitionIDs(ids, env.Config.Threads)}, nil
+}
+
+func (w *upsertWorkload) Run(collection CollectionAPI, worker *Worker) error {
+	partition := w.partitions[worker.ID]
+	if len(partition) == 0 {
+		return ErrStopWorker
+	}
+	// Half of the partition is picked from, so upserts both insert and update documents
+	id := partition[worker.Random.RandomIntn(max(len(partition)/2, 1))]
+	update, opts := newUpdate(worker.Random, w.env.Config)
+	opts.SetUpsert(true)
+	err := w.env.Config.Retry.run(w.env.RetryStats, func() error {
+		_, err := collection.UpdateOne(context.Background(), bson.M{"_id": id}, update, opts)
+		return err
+	})
+	w.env.Writes.Updated(id, update, err)
+	return err
+}
+
+// partitionIDs spreads the IDs across the workers round-robin
+func partitionIDs(ids []primitive.ObjectID, workers int) [][]primitive.ObjectID {
+	partitions := make([][]primitive.ObjectID, workers)
+	for i, id := range ids {
+		partitions[i%workers] = append(partitions[i%workers], id)
+	}
+	return partitions
+}
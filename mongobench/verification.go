@@ -0,0 +1,220 @@
+package mongobench
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// verifyRetries are the attempts to read the collection back, which may fail right after a failover
+var verifyRetries = RetryPolicy{MaxAttempts: 5, Backoff: 200 * time.Millisecond, MaxBackoff: 2 * time.Second}
+
+// maxReportedDocuments limits the lost and phantom documents logged by a verification
+const maxReportedDocuments = 10
+
+// Verification is the outcome of reading back the documents a test wrote
+type Verification struct {
+	Checked    int64 // documents written by the test
+	Lost       int64 // acknowledged writes missing from the collection: absent documents or older rnd values
+	Phantom    int64 // documents present although their deletion was acknowledged, or never written at all
+	Uncertain  int64 // documents whose last writes failed, so either outcome is correct
+	Unverified int64 // documents whose last acknowledged write has no known rnd value, so only their presence is checked
+}
+
+// Passed reports whether no write was lost and no phantom document was found
+func (v Verification) Passed() bool {
+	return v.Lost == 0 && v.Phantom == 0
+}
+
+func (v Verification) String() string {
+	return fmt.Sprintf("%d documents checked, %d lost writes, %d phantom writes, %d uncertain, %d unverified",
+		v.Checked, v.Lost, v.Phantom, v.Uncertain, v.Unverified)
+}
+
+// documentState is a state a document can be in after a write
+type documentState struct {
+	exists bool
+	rnd    int64
+	hasRnd bool // whether the write set a known rnd value
+}
+
+func (s documentState) matches(exists bool, rnd int64, hasRnd bool) bool {
+	if s.exists != exists {
+		return false
+	}
+	return !s.exists || !s.hasRnd || (hasRnd && s.rnd == rnd)
+}
+
+// writeRecord holds the states a document can be in: the state of its last acknowledged write, followed by the states
+// of the writes that failed since, as a failed write may or may not have been applied
+type writeRecord struct {
+	states []documentState
+	any    bool // the first write failed and the state before it is unknown
+}
+
+// WriteLog records the writes of a test, so that they can be verified once the test finished. Every document must be
+// written by one worker at a time, so the order of its writes is known. A nil WriteLog records nothing.
+type WriteLog struct {
+	mu      sync.Mutex
+	records map[primitive.ObjectID]*writeRecord
+	fresh   bool // the collection started empty, so every document must have been written by the test
+}
+
+func newWriteLog(fresh bool) *WriteLog {
+	return &WriteLog{records: make(map[primitive.ObjectID]*writeRecord), fresh: fresh}
+}
+
+// Wrote records the insert or replacement of a document
+func (l *WriteLog) Wrote(id primitive.ObjectID, doc bson.M, err error) {
+	rnd, hasRnd := doc["rnd"].(int64)
+	l.record(id, documentState{exists: true, rnd: rnd, hasRnd: hasRnd}, err)
+}
+
+// Updated records an update or upsert of a document; the written rnd value is known for updates setting it with $set
+func (l *WriteLog) Updated(id primitive.ObjectID, update interface{}, err error) {
+	rnd, hasRnd := setRnd(update)
+	l.record(id, documentState{exists: true, rnd: rnd, hasRnd: hasRnd}, err)
+}
+
+// setRnd returns the rnd value an update document sets with $set, if any
+func setRnd(update interface{}) (int64, bool) {
+	var set interface{}
+	switch update := update.(type) {
+	case bson.M:
+		set = update["$set"]
+	case bson.D:
+		for _, element := range update {
+			if element.Key == "$set" {
+				set = element.Value
+			}
+		}
+	}
+	switch set := set.(type) {
+	case bson.M:
+		rnd, ok := set["rnd"].(int64)
+		return rnd, ok
+	case bson.D:
+		for _, element := range set {
+			if element.Key == "rnd" {
+				rnd, ok := element.Value.(int64)
+				return rnd, ok
+			}
+		}
+	}
+	return 0, false
+}
+
+// Deleted records the deletion of a document
+func (l *WriteLog) Deleted(id primitive.ObjectID, err error) {
+	l.record(id, documentState{}, err)
+}
+
+func (l *WriteLog) record(id primitive.ObjectID, state documentState, err error) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	record, ok := l.records[id]
+	if !ok {
+		record = &writeRecord{}
+		l.records[id] = record
+	}
+	switch {
+	case err == nil:
+		record.states = []documentState{state}
+		record.any = false
+	case !ok:
+		record.states = []documentState{state}
+		record.any = true
+	default:
+		record.states = append(record.states, state)
+	}
+}
+
+// verify reads back all documents of the collection and compares them with the recorded writes
+func (l *WriteLog) verify(collection CollectionAPI) (Verification, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.records) == 0 {
+		return Verification{}, nil
+	}
+
+	var docs []bson.M
+	err := verifyRetries.run(&RetryStats{}, func() error {
+		cursor, err := collection.Find(context.Background(), bson.M{}, options.Find().SetProjection(bson.M{"_id": 1, "rnd": 1}))
+		if err != nil {
+			return err
+		}
+		docs = nil
+		return cursor.All(context.Background(), &docs)
+	})
+	if err != nil {
+		return Verification{}, fmt.Errorf("failed to read back the documents: %v", err)
+	}
+
+	verification := Verification{Checked: int64(len(l.records))}
+	reported := 0
+	report := func(format string, args ...interface{}) {
+		if reported++; reported <= maxReportedDocuments {
+			logger.Printf(format, args...)
+		}
+	}
+	found := make(map[primitive.ObjectID]bool, len(docs))
+	for _, doc := range docs {
+		id, ok := doc["_id"].(primitive.ObjectID)
+		record := l.records[id]
+		if !ok || record == nil {
+			if l.fresh {
+				verification.Phantom++
+				report("Phantom write: document %v was never written", doc["_id"])
+			}
+			continue
+		}
+		found[id] = true
+		rnd, hasRnd := doc["rnd"].(int64)
+		checkDocument(&verification, record, id, true, rnd, hasRnd, report)
+	}
+	for id, record := range l.records {
+		if !found[id] {
+			checkDocument(&verification, record, id, false, 0, false, report)
+		}
+	}
+	return verification, nil
+}
+
+// checkDocument compares the state a document was found in with the states its writes allow
+func checkDocument(verification *Verification, record *writeRecord, id primitive.ObjectID, exists bool, rnd int64, hasRnd bool, report func(string, ...interface{})) {
+	if record.any || len(record.states) > 1 {
+		verification.Uncertain++
+	}
+	if record.any {
+		return
+	}
+	if last := record.states[0]; last.exists && !last.hasRnd {
+		verification.Unverified++
+	}
+	deleted := true
+	for _, state := range record.states {
+		if state.matches(exists, rnd, hasRnd) {
+			return
+		}
+		deleted = deleted && !state.exists
+	}
+	switch {
+	case !exists:
+		verification.Lost++
+		report("Lost write: document %s is missing", id.Hex())
+	case deleted:
+		verification.Phantom++
+		report("Phantom write: document %s is present after its deletion", id.Hex())
+	default:
+		verification.Lost++
+		report("Lost write: document %s has rnd %d instead of the last written value", id.Hex(), rnd)
+	}
+}
@@ -0,0 +1,91 @@
+package mongobench
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Workload is a test type of the runner. A workload is created for every test and set up once; then every worker
+// runs it until a stop condition is reached, and it is torn down after all workers finished. Its columns are added
+// to the per-second results.
+type Workload interface {
+	MetricsSource
+	// Setup prepares the workload and the collection before the workers start
+	Setup(collection CollectionAPI) error
+	// Run performs one operation of a worker; the runner measures it and counts it if it succeeded
+	Run(collection CollectionAPI, worker *Worker) error
+	// Teardown runs after all workers finished, e.g. to verify the results
+	Teardown(collection CollectionAPI) error
+}
+
+// WorkloadEnv holds what a workload needs to build its operations
+type WorkloadEnv struct {
+	Config      TestingConfig
+	RetryStats  *RetryStats
+	FetchDocIDs func(CollectionAPI, int64, string) ([]primitive.ObjectID, error)
+	Data        []byte    // payload of large documents
+	Writes      *WriteLog // records the writes to verify, nil unless TestingConfig.Verify is set
+}
+
+// Worker is the state of a single worker goroutine
+type Worker struct {
+	ID      int
+	Random  *Randomizer
+	Stopped <-chan struct{} // closed once a stop condition is reached
+}
+
+var (
+	// ErrStopWorker is returned by Run when the worker has no work left; the worker ends without counting the operation
+	ErrStopWorker = errors.New("no work left for the worker")
+	// ErrNoEffect is returned by Run when the operation had nothing to do, it is neither counted nor a failure
+	ErrNoEffect = errors.New("the operation had no effect")
+)
+
+// WorkloadDefinition makes a workload selectable by its name with -type
+type WorkloadDefinition struct {
+	Name string
+	// Fresh workloads start from an empty collection, which is dropped before the test if dropping is enabled
+	Fresh bool
+	New   func(env WorkloadEnv) (Workload, error)
+}
+
+var workloads = map[string]WorkloadDefinition{}
+
+// RegisterWorkload adds a workload to the registry; call it from an init function of the file declaring the workload
+func RegisterWorkload(definition WorkloadDefinition) {
+	if _, exists := workloads[definition.Name]; exists {
+		panic(fmt.Sprintf("workload %q is registered twice", definition.Name))
+	}
+	workloads[definition.Name] = definition
+}
+
+// WorkloadNames returns the names of all registered workloads
+func WorkloadNames() []string {
+	names := make([]string, 0, len(workloads))
+	for name := range workloads {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupWorkload fails if the test type is unknown, instead of running a test that does nothing
+func lookupWorkload(testType string) (WorkloadDefinition, error) {
+	definition, ok := workloads[testType]
+	if !ok {
+		return definition, fmt.Errorf("unknown test type %q, expected one of %s", testType, strings.Join(WorkloadNames(), ", "))
+	}
+	return definition, nil
+}
+
+// BaseWorkload can be embedded by workloads and provides no-op implementations of the optional parts of a Workload
+type BaseWorkload struct{}
+
+func (BaseWorkload) Setup(CollectionAPI) error    { return nil }
+func (BaseWorkload) Teardown(CollectionAPI) error { return nil }
+func (BaseWorkload) Columns() []string            { return nil }
+func (BaseWorkload) Values() []string             { return nil }